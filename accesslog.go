@@ -0,0 +1,207 @@
+package wrap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/torniker/wrap/request"
+)
+
+// list of transport names used in access log, sub-requests of Ctx.Call and batch are logged as sub
+const (
+	TransportHTTP  = "http"
+	TransportCLI   = "cli"
+	TransportWS    = "ws"
+	TransportStdio = "stdio"
+	TransportSub   = "sub"
+)
+
+// Redacted replaces values of sensitive flags in access log
+const Redacted = "[REDACTED]"
+
+// DefaultRedact lists flags, query params and headers which are redacted by default, names are case insensitive
+var DefaultRedact = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"Proxy-Authorization",
+	"X-Api-Key",
+	"password",
+	"token",
+	"access_token",
+	"secret",
+}
+
+// AccessEntry describes a single served request
+type AccessEntry struct {
+	Time       time.Time           `json:"time"`
+	Transport  string              `json:"transport"`
+	RequestID  string              `json:"request_id"`
+	RemoteAddr string              `json:"remote_addr,omitempty"`
+	Action     string              `json:"action"`
+	Path       string              `json:"path"`
+	Query      url.Values          `json:"query,omitempty"`
+	Flags      map[string][]string `json:"flags,omitempty"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Status     int                 `json:"status"`
+	Size       int                 `json:"size"`
+	Duration   time.Duration       `json:"duration"`
+	UserID     string              `json:"user_id,omitempty"`
+}
+
+// AccessLogFormat writes access entry to w
+type AccessLogFormat func(w io.Writer, e AccessEntry) error
+
+// CommonLogFormat writes entry in Common Log Format, the protocol is replaced with transport name
+func CommonLogFormat(w io.Writer, e AccessEntry) error {
+	target := e.Path
+	if len(e.Query) > 0 {
+		target += "?" + e.Query.Encode()
+	}
+	_, err := fmt.Fprintf(w, "%v - %v [%v] \"%v %v %v\" %v %v\n",
+		dash(e.RemoteAddr),
+		dash(e.UserID),
+		e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Action,
+		target,
+		strings.ToUpper(e.Transport),
+		e.Status,
+		e.Size,
+	)
+	return err
+}
+
+// JSONLogFormat writes entry as a single JSON line, duration is in milliseconds
+func JSONLogFormat(w io.Writer, e AccessEntry) error {
+	type entry AccessEntry
+	return json.NewEncoder(w).Encode(struct {
+		entry
+		Duration float64 `json:"duration"`
+	}{
+		entry:    entry(e),
+		Duration: float64(e.Duration) / float64(time.Millisecond),
+	})
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// AccessLog records every request served by the app once it is done.
+// Entries are written to Out in Format, if Out is nil entries are written to Prog.Logger.
+// Transports which use the terminal or stdout themselves, REPL and stdio, move entries written to
+// os.Stdout out of their way.
+// Values of flags, query params and headers listed in Redact are replaced with Redacted.
+type AccessLog struct {
+	mu     sync.Mutex
+	Out    io.Writer
+	Format AccessLogFormat
+	Redact []string
+}
+
+// NewAccessLog returns access log writing to out in format with default redacted flags
+func NewAccessLog(out io.Writer, format AccessLogFormat) *AccessLog {
+	if format == nil {
+		format = CommonLogFormat
+	}
+	return &AccessLog{
+		Out:    out,
+		Format: format,
+		Redact: DefaultRedact,
+	}
+}
+
+func (a *AccessLog) record(ctx *Ctx, start time.Time) {
+	if a == nil {
+		return
+	}
+	e := AccessEntry{
+		Time:      start,
+		Transport: transport(ctx.Request),
+		RequestID: ctx.id,
+		Action:    ctx.Request.Action().String(),
+		Path:      ctx.Request.Path().URL().Path,
+		Query:     a.redact(ctx.Request.Path().URL().Query()),
+		Header:    a.redact(ctx.Request.Header()),
+		Status:    ctx.Response.Status(),
+		Size:      ctx.Response.Size(),
+		Duration:  time.Since(start),
+	}
+	if e.Status == 0 {
+		e.Status = http.StatusOK
+	}
+	if r, ok := ctx.Request.(*request.HTTP); ok {
+		e.RemoteAddr = r.RemoteAddr()
+	} else {
+		// flags of HTTP requests are the query which is logged already
+		e.Flags = a.redact(ctx.Request.Flags())
+	}
+	if ctx.User != nil {
+		e.UserID = ctx.User.ID().String()
+	}
+	if a.Out == nil {
+		ctx.appLogger().Info("access",
+			"transport", e.Transport,
+			"request_id", e.RequestID,
+			"action", e.Action,
+			"path", e.Path,
+			"query", e.Query.Encode(),
+			"status", e.Status,
+			"size", e.Size,
+			"duration", e.Duration,
+			"user_id", e.UserID,
+		)
+		return
+	}
+	out := a.Out
+	if ctx.console != nil {
+		out = ctx.console.writer(out)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	err := a.Format(out, e)
+	if err != nil {
+		ctx.appLogger().Error("access log failed", "error", err)
+	}
+}
+
+// redact returns copy of values with sensitive values replaced
+func (a *AccessLog) redact(values map[string][]string) map[string][]string {
+	if len(values) == 0 {
+		return nil
+	}
+	redacted := make(map[string][]string, len(values))
+	for key, val := range values {
+		redacted[key] = val
+		for _, name := range a.Redact {
+			if strings.EqualFold(key, name) {
+				redacted[key] = []string{Redacted}
+				break
+			}
+		}
+	}
+	return redacted
+}
+
+func transport(r request.Request) string {
+	switch r.(type) {
+	case *request.HTTP:
+		return TransportHTTP
+	case *request.CLI:
+		return TransportCLI
+	case *request.WS:
+		return TransportWS
+	case *request.Stdio:
+		return TransportStdio
+	}
+	return TransportSub
+}
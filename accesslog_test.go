@@ -0,0 +1,71 @@
+package wrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLogCommonLogFormat(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var buf bytes.Buffer
+	p.AccessLog = NewAccessLog(&buf, CommonLogFormat)
+	p.Get("users", func(c *Ctx) error {
+		return c.JSON("ok")
+	})
+	r := httptest.NewRequest(http.MethodGet, "/users?token=secret&page=2", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.Regexp(`^10\.0\.0\.1:1234 - - \[.+\] "GET /users\?page=2&token=%5BREDACTED%5D HTTP" 200 14\n$`, buf.String())
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var buf bytes.Buffer
+	p.AccessLog = NewAccessLog(&buf, JSONLogFormat)
+	r := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set(RequestIDHeader, "req-1")
+	p.ServeHTTP(httptest.NewRecorder(), r)
+	var e map[string]interface{}
+	assert.NoError(json.Unmarshal(buf.Bytes(), &e))
+	assert.Equal("http", e["transport"])
+	assert.Equal("req-1", e["request_id"])
+	assert.Equal("/missing", e["path"])
+	assert.Equal(float64(http.StatusNotFound), e["status"])
+	assert.Equal([]interface{}{Redacted}, e["header"].(map[string]interface{})["Authorization"])
+	assert.NotContains(buf.String(), "Bearer secret")
+}
+
+func TestAccessLogSubRequest(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var buf bytes.Buffer
+	p.AccessLog = NewAccessLog(&buf, JSONLogFormat)
+	p.Get("inner", func(c *Ctx) error {
+		return c.NotFound()
+	})
+	p.Get("outer", func(c *Ctx) error {
+		c.Call().Read("inner").Bind(nil)
+		return c.JSON("ok")
+	})
+	r := httptest.NewRequest(http.MethodGet, "/outer", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	p.ServeHTTP(httptest.NewRecorder(), r)
+	dec := json.NewDecoder(&buf)
+	var sub, outer map[string]interface{}
+	assert.NoError(dec.Decode(&sub))
+	assert.NoError(dec.Decode(&outer))
+	assert.Equal(TransportSub, sub["transport"])
+	assert.Equal("req-1", sub["request_id"])
+	assert.Equal("inner", sub["path"])
+	assert.Equal(float64(http.StatusNotFound), sub["status"])
+	assert.Equal(TransportHTTP, outer["transport"])
+}
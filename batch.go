@@ -0,0 +1,306 @@
+package wrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/torniker/wrap/request"
+	"github.com/torniker/wrap/response"
+)
+
+// DefaultBatchMaxOps is the default limit of operations in a single batch
+const DefaultBatchMaxOps = 100
+
+// DefaultBatchMaxDepth is the default limit of batches nested in each other
+const DefaultBatchMaxDepth = 2
+
+// BatchOp is a single operation of batch request.
+// Path, flags and body may reference results of previous operations as ${id.body.data.id},
+// a reference which is the whole JSON string of the body is replaced with the referenced value
+// and values referenced in path are escaped so that they stay within their segment or query parameter.
+type BatchOp struct {
+	ID     string              `json:"id,omitempty"`
+	Action string              `json:"action"`
+	Path   string              `json:"path"`
+	Flags  map[string][]string `json:"flags,omitempty"`
+	Body   json.RawMessage     `json:"body,omitempty"`
+}
+
+// BatchRequest is body of batch request, operations run in order unless Parallel is set
+type BatchRequest struct {
+	Parallel bool      `json:"parallel"`
+	Ops      []BatchOp `json:"ops"`
+}
+
+// BatchResult is response of a single operation
+type BatchResult struct {
+	ID     string      `json:"id,omitempty"`
+	Status int         `json:"status"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+// BatchOptions configures batch endpoint
+type BatchOptions struct {
+	// MaxOps limits number of operations, DefaultBatchMaxOps is used if it is zero
+	MaxOps int
+	// Concurrency limits operations running at once in parallel batches, zero means no limit
+	Concurrency int
+	// MaxDepth limits batches nested in each other through any batch endpoint or sub-request
+	// counting this one, DefaultBatchMaxDepth is used if it is zero
+	MaxDepth int
+}
+
+// Batch registers POST handler for the pattern which executes BatchRequest operations
+// as sub-requests through DefaultHandler and responds with the list of BatchResult.
+// Operations inherit context, user, request id and headers of the batch request,
+// operations calling the batch endpoint itself are rejected.
+func (p *Prog) Batch(pattern string, opts BatchOptions, m ...Middleware) {
+	if opts.MaxOps == 0 {
+		opts.MaxOps = DefaultBatchMaxOps
+	}
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultBatchMaxDepth
+	}
+	var self *node
+	p.Post(pattern, func(ctx *Ctx) error {
+		if ctx.batchDepth >= opts.MaxDepth {
+			return ctx.BadRequest(fmt.Sprintf("batches can be nested at most %v deep", opts.MaxDepth))
+		}
+		var b BatchRequest
+		err := ctx.Bind(&b)
+		if err != nil {
+			return err
+		}
+		if len(b.Ops) > opts.MaxOps {
+			return ctx.BadRequest(fmt.Sprintf("batch has %v operations, at most %v are allowed", len(b.Ops), opts.MaxOps))
+		}
+		return ctx.JSON(ctx.batch(b, opts.Concurrency, self))
+	}, m...)
+	self = p.Router.insert(pattern)
+}
+
+// batch runs the operations, operations which reference others wait for them
+func (ctx *Ctx) batch(b BatchRequest, concurrency int, self *node) []BatchResult {
+	results := make([]BatchResult, len(b.Ops))
+	done := make([]chan struct{}, len(b.Ops))
+	index := make(map[string]int, len(b.Ops))
+	for i, op := range b.Ops {
+		done[i] = make(chan struct{})
+		if op.ID != "" {
+			index[op.ID] = i
+		}
+	}
+	run := func(i int) {
+		defer close(done[i])
+		results[i] = ctx.batchOp(b.Ops[i], i, index, results, done, self)
+	}
+	if !b.Parallel {
+		for i := range b.Ops {
+			run(i)
+		}
+		return results
+	}
+	if concurrency <= 0 {
+		concurrency = len(b.Ops)
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := range b.Ops {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// dependencies are awaited before taking a slot so that waiting operations do not block them
+			for _, dep := range refs(b.Ops[i]) {
+				if j, ok := index[dep]; ok && j < i {
+					<-done[j]
+				}
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			run(i)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// batchOp resolves references of the operation and dispatches it
+func (ctx *Ctx) batchOp(op BatchOp, i int, index map[string]int, results []BatchResult, done []chan struct{}, self *node) BatchResult {
+	res := BatchResult{ID: op.ID}
+	values := make(map[string]interface{})
+	for _, dep := range refs(op) {
+		j, ok := index[dep]
+		if !ok || j >= i {
+			res.Status, res.Body = errorResponse(ErrorBadRequest{Message: fmt.Sprintf("unknown operation reference: %v", dep)})
+			return res
+		}
+		<-done[j]
+		if results[j].Status >= http.StatusBadRequest {
+			res.Status, res.Body = errorResponse(ErrorStatus{Status: http.StatusFailedDependency, Message: fmt.Sprintf("operation %v failed", dep)})
+			return res
+		}
+		// numbers are kept as json.Number so that ids are not formatted as floats
+		var v interface{}
+		b, _ := json.Marshal(results[j])
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		dec.Decode(&v)
+		values[dep] = v
+	}
+	action := request.NewActionFromString(op.Action)
+	if !action.IsValid() {
+		res.Status, res.Body = errorResponse(ErrorBadRequest{Message: fmt.Sprintf("invalid action: %v", op.Action)})
+		return res
+	}
+	path, body, flags, err := resolve(op, values)
+	if err != nil {
+		res.Status, res.Body = errorResponse(ErrorBadRequest{Message: err.Error()})
+		return res
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		res.Status, res.Body = errorResponse(ErrorBadRequest{Message: fmt.Sprintf("invalid path: %v", path)})
+		return res
+	}
+	if ctx.Prog.Router.lookup(u) == self {
+		res.Status, res.Body = errorResponse(ErrorBadRequest{Message: "batch operations cannot call the batch endpoint"})
+		return res
+	}
+	req := request.NewRequest(action, u, nil).SetFlags(flags).SetHeader(ctx.Request.Header().Clone())
+	if len(body) > 0 {
+		req.SetInput(bytes.NewReader(body))
+	}
+	subCtx := ctx.Prog.NewCtx(req, response.NewResponse())
+	subCtx.context = ctx.Context()
+	subCtx.User = ctx.User
+	subCtx.id = ctx.id
+	subCtx.console = ctx.console
+	subCtx.batchDepth = ctx.batchDepth + 1
+	start := time.Now()
+	err = ctx.Prog.handle(subCtx)
+	if err != nil {
+		subCtx.Error(err)
+	}
+	ctx.Prog.AccessLog.record(subCtx, start)
+	res.Status = subCtx.Response.Status()
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	res.Body = subCtx.Response.Output()
+	return res
+}
+
+var (
+	refPattern = regexp.MustCompile(`\$\{([^}.]+)((?:\.[^}.]+)*)\}`)
+	// wholeRefPattern matches JSON strings which consist of a single reference
+	wholeRefPattern = regexp.MustCompile(`"` + refPattern.String() + `"`)
+)
+
+// refs returns ids of operations referenced by op
+func refs(op BatchOp) []string {
+	var ids []string
+	collect := func(s string) {
+		for _, m := range refPattern.FindAllStringSubmatch(s, -1) {
+			ids = append(ids, m[1])
+		}
+	}
+	collect(op.Path)
+	collect(string(op.Body))
+	for _, vals := range op.Flags {
+		for _, val := range vals {
+			collect(val)
+		}
+	}
+	return ids
+}
+
+// resolve replaces references in path, body and flags with the values
+func resolve(op BatchOp, values map[string]interface{}) (string, []byte, map[string][]string, error) {
+	var err error
+	lookup := func(m []string) interface{} {
+		v, e := lookupRef(values[m[1]], strings.Split(strings.TrimPrefix(m[2], "."), "."))
+		if e != nil && err == nil {
+			err = fmt.Errorf("invalid reference %v: %v", m[0], e)
+		}
+		return v
+	}
+	replace := func(s string) string {
+		return refPattern.ReplaceAllStringFunc(s, func(ref string) string {
+			return fmt.Sprint(lookup(refPattern.FindStringSubmatch(ref)))
+		})
+	}
+	path := resolvePath(op.Path, lookup)
+	flags := make(map[string][]string, len(op.Flags))
+	for key, vals := range op.Flags {
+		flags[key] = make([]string, len(vals))
+		for i, val := range vals {
+			flags[key][i] = replace(val)
+		}
+	}
+	body := op.Body
+	if len(body) > 0 {
+		// whole JSON strings keep the type of the referenced value, other references are embedded as text
+		body = wholeRefPattern.ReplaceAllFunc(body, func(ref []byte) []byte {
+			b, _ := json.Marshal(lookup(wholeRefPattern.FindStringSubmatch(string(ref))))
+			return b
+		})
+		body = refPattern.ReplaceAllFunc(body, func(ref []byte) []byte {
+			b, _ := json.Marshal(fmt.Sprint(lookup(refPattern.FindStringSubmatch(string(ref)))))
+			return b[1 : len(b)-1]
+		})
+	}
+	return path, body, flags, err
+}
+
+// resolvePath replaces references in path with escaped values, references after "?" are escaped as query
+func resolvePath(path string, lookup func(m []string) interface{}) string {
+	query := strings.IndexByte(path, '?')
+	var b strings.Builder
+	last := 0
+	for _, loc := range refPattern.FindAllStringIndex(path, -1) {
+		b.WriteString(path[last:loc[0]])
+		v := fmt.Sprint(lookup(refPattern.FindStringSubmatch(path[loc[0]:loc[1]])))
+		if query >= 0 && loc[0] > query {
+			b.WriteString(url.QueryEscape(v))
+		} else {
+			b.WriteString(url.PathEscape(v))
+		}
+		last = loc[1]
+	}
+	b.WriteString(path[last:])
+	return b.String()
+}
+
+// lookupRef walks v by keys of objects and indexes of arrays
+func lookupRef(v interface{}, path []string) (interface{}, error) {
+	for _, key := range path {
+		if key == "" {
+			continue
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			next, ok := val[key]
+			if !ok {
+				return nil, fmt.Errorf("%v not found", key)
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(val) {
+				return nil, fmt.Errorf("index %v out of range", key)
+			}
+			v = val[i]
+		default:
+			return nil, fmt.Errorf("%v not found", key)
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,167 @@
+package wrap
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Batch("batch", BatchOptions{MaxOps: 5})
+	p.Post("users", func(c *Ctx) error {
+		var in struct {
+			Name string `json:"name" validate:"required"`
+		}
+		err := c.Bind(&in)
+		if err != nil {
+			return err
+		}
+		return c.JSON(map[string]interface{}{"id": 1234567, "name": in.Name})
+	})
+	p.Post("users/:id/posts", func(c *Ctx) error {
+		var in struct {
+			Author int    `json:"author"`
+			Title  string `json:"title"`
+		}
+		err := c.Bind(&in)
+		if err != nil {
+			return err
+		}
+		return c.JSON(map[string]interface{}{"user": c.Param("id"), "author": in.Author, "title": in.Title, "tag": c.Request.Flags()["tag"]})
+	})
+	w := serveRequest(p, http.MethodPost, "/batch", strings.NewReader(`{"ops":[
+		{"id":"user","action":"post","path":"users","body":{"name":"ann"}},
+		{"action":"post","path":"users/${user.body.data.id}/posts","flags":{"tag":["${user.body.data.name}"]},"body":{"author":"${user.body.data.id}","title":"by ${user.body.data.name}"}},
+		{"action":"post","path":"users","body":{}},
+		{"action":"get","path":"missing"},
+		{"action":"post","path":"users/${nope.body.id}/posts"}
+	]}`), nil)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{"data":[
+		{"id":"user","status":200,"body":{"data":{"id":1234567,"name":"ann"}}},
+		{"status":200,"body":{"data":{"user":"1234567","author":1234567,"title":"by ann","tag":["ann"]}}},
+		{"status":422,"body":{"errors":[{"path":["name"],"message":"is required"}]}},
+		{"status":404,"body":{"message":"not found"}},
+		{"status":400,"body":{"message":"unknown operation reference: nope"}}
+	]}`, w.Body.String())
+
+	w = serveRequest(p, http.MethodPost, "/batch", strings.NewReader(`{"ops":[{},{},{},{},{},{}]}`), nil)
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
+
+func TestBatchParallel(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Batch("batch", BatchOptions{Concurrency: 2})
+	var running, max int32
+	p.Get("slow/:id", func(c *Ctx) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return c.JSON(c.Param("id"))
+	})
+	p.Get("fail", func(c *Ctx) error {
+		return c.BadRequest("fail")
+	})
+	w := serveRequest(p, http.MethodPost, "/batch", strings.NewReader(`{"parallel":true,"ops":[
+		{"id":"a","action":"get","path":"slow/1"},
+		{"action":"get","path":"slow/2"},
+		{"action":"get","path":"slow/3"},
+		{"action":"get","path":"slow/${a.body.data}"},
+		{"id":"f","action":"get","path":"fail"},
+		{"action":"get","path":"slow/${f.body}"}
+	]}`), nil)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{"data":[
+		{"id":"a","status":200,"body":{"data":"1"}},
+		{"status":200,"body":{"data":"2"}},
+		{"status":200,"body":{"data":"3"}},
+		{"status":200,"body":{"data":"1"}},
+		{"id":"f","status":400,"body":{"message":"fail"}},
+		{"status":424,"body":{"message":"operation f failed"}}
+	]}`, w.Body.String())
+	assert.Equal(int32(2), atomic.LoadInt32(&max))
+}
+
+func TestBatchHeadersAndNesting(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Batch("batch", BatchOptions{MaxOps: 2})
+	p.Get("me", func(c *Ctx) error {
+		return c.JSON(c.Header("Authorization"))
+	})
+	w := serveRequest(p, http.MethodPost, "/batch", strings.NewReader(`{"ops":[
+		{"action":"get","path":"me"},
+		{"action":"post","path":"/batch","body":{"ops":[{"action":"get","path":"me"}]}}
+	]}`), map[string]string{"Authorization": "Bearer t"})
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{"data":[
+		{"status":200,"body":{"data":"Bearer t"}},
+		{"status":400,"body":{"message":"batch operations cannot call the batch endpoint"}}
+	]}`, w.Body.String())
+}
+
+func TestBatchEscapesReferences(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Batch("batch", BatchOptions{})
+	p.Get("names", func(c *Ctx) error {
+		return c.JSON("a/../admin?x=1")
+	})
+	p.Get("names/:name", func(c *Ctx) error {
+		return c.JSON(map[string]interface{}{"name": c.Param("name"), "q": c.Request.Flags()["q"]})
+	})
+	w := serveRequest(p, http.MethodPost, "/batch", strings.NewReader(`{"ops":[
+		{"id":"n","action":"get","path":"names"},
+		{"action":"get","path":"names/${n.body.data}?q=${n.body.data}"}
+	]}`), nil)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{"data":[
+		{"id":"n","status":200,"body":{"data":"a/../admin?x=1"}},
+		{"status":200,"body":{"data":{"name":"a/../admin?x=1","q":["a/../admin?x=1"]}}}
+	]}`, w.Body.String())
+}
+
+func TestBatchMaxDepth(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Batch("batch", BatchOptions{})
+	p.Batch("other", BatchOptions{})
+	p.Get("me", func(c *Ctx) error {
+		return c.JSON("me")
+	})
+	// a handler reaching the batch endpoint through a sub-request
+	p.Post("proxy", func(c *Ctx) error {
+		var out interface{}
+		err := c.Call().Input(map[string]interface{}{"ops": []BatchOp{{Action: "post", Path: "other", Body: []byte(`{"ops":[]}`)}}}).Create("batch").Bind(&out)
+		if err != nil {
+			return err
+		}
+		return c.JSON(out)
+	})
+	w := serveRequest(p, http.MethodPost, "/batch", strings.NewReader(`{"ops":[
+		{"action":"post","path":"other","body":{"ops":[{"action":"get","path":"me"}]}},
+		{"action":"post","path":"other","body":{"ops":[{"action":"post","path":"batch","body":{"ops":[]}}]}}
+	]}`), nil)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{"data":[
+		{"status":200,"body":{"data":[{"status":200,"body":{"data":"me"}}]}},
+		{"status":200,"body":{"data":[{"status":400,"body":{"message":"batches can be nested at most 2 deep"}}]}}
+	]}`, w.Body.String())
+
+	w = serveRequest(p, http.MethodPost, "/other", strings.NewReader(`{"ops":[{"action":"post","path":"proxy"}]}`), nil)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{"data":[{"status":200,"body":{"data":[{"status":400,"body":{"message":"batches can be nested at most 2 deep"}}]}}]}`, w.Body.String())
+}
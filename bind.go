@@ -0,0 +1,113 @@
+package wrap
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/torniker/wrap/request"
+)
+
+// Bind decodes request body into v, fills struct fields tagged with param, query, header and cookie
+// from path params, flags, headers and cookies and validates the result against validate tags.
+// Body is decoded according to its content type, unknown types respond with ErrorUnsupportedMediaType,
+// too large body with ErrorPayloadTooLarge, malformed body with ErrorBadRequest and invalid values
+// with ErrorUnprocessableEntity.
+//
+//	type Input struct {
+//		ID    int    `json:"id" param:"id"`
+//		Page  int    `json:"page" query:"page" validate:"min=1"`
+//		Token string `json:"-" header:"X-Token" validate:"required"`
+//		Name  string `json:"name" validate:"required,max=64"`
+//	}
+func (ctx *Ctx) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ctx.InternalServerError(fmt.Errorf("bind: expected non-nil pointer, got %T", v))
+	}
+	if m, ok := ctx.Request.(memoryLimiter); ok {
+		m.SetMaxMemory(ctx.Prog.MaxMemory)
+	}
+	err := ctx.Request.Bind(v)
+	if err != nil && err != io.EOF {
+		return ctx.bodyError(err)
+	}
+	errs := ctx.bindValues(rv.Elem())
+	if len(errs) > 0 {
+		return ctx.UnprocessableEntity(errs)
+	}
+	return ctx.Validate(v)
+}
+
+// bindValues sets fields of struct v from path params, flags, headers and cookies
+func (ctx *Ctx) bindValues(v reflect.Value) FieldErrors {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs FieldErrors
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+		if f.Anonymous && fv.Kind() == reflect.Struct {
+			errs = append(errs, ctx.bindValues(fv)...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		values, ok := ctx.lookup(f.Tag)
+		if !ok {
+			continue
+		}
+		err := request.SetValues(fv, values)
+		if err != nil {
+			errs = append(errs, FieldError{
+				Path:    []string{fieldName(f)},
+				Message: err.Error(),
+			})
+		}
+	}
+	return errs
+}
+
+// lookup returns values for the field tag, path params take precedence over flags, flags over headers
+// and headers over cookies
+func (ctx *Ctx) lookup(tag reflect.StructTag) ([]string, bool) {
+	if name, ok := tag.Lookup("param"); ok {
+		if val, ok := ctx.Request.Params()[name]; ok {
+			return []string{val}, true
+		}
+	}
+	if name, ok := tag.Lookup("query"); ok {
+		if vals := ctx.Request.Flags()[name]; len(vals) > 0 {
+			return vals, true
+		}
+	}
+	if name, ok := tag.Lookup("header"); ok {
+		if vals := ctx.Request.Header().Values(name); len(vals) > 0 {
+			return vals, true
+		}
+	}
+	if name, ok := tag.Lookup("cookie"); ok {
+		if c := ctx.Cookie(name); c != nil {
+			return []string{c.Value}, true
+		}
+	}
+	return nil, false
+}
+
+// fieldName returns name of the field in JSON, fields omitted from JSON are named after their source
+func fieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name != "" && name != "-" {
+		return name
+	}
+	for _, key := range []string{"param", "query", "header", "cookie"} {
+		if name, ok := f.Tag.Lookup(key); ok {
+			return name
+		}
+	}
+	return f.Name
+}
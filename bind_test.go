@@ -0,0 +1,277 @@
+package wrap
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+)
+
+type bindItem struct {
+	Name string `json:"name" validate:"required,max=5"`
+	Qty  int    `json:"qty" validate:"min=1"`
+}
+
+type bindInput struct {
+	ID      int           `json:"id" param:"id"`
+	Page    int           `json:"page" query:"page" validate:"min=1,max=100"`
+	Tags    []string      `json:"tags" query:"tag"`
+	Since   time.Duration `json:"since" query:"since"`
+	Account uuid.UUID     `json:"account" query:"account"`
+	Token   string        `json:"-" header:"X-Token" validate:"required"`
+	Email   string        `json:"email" validate:"required,regex=^[^@]+@[^@]+$"`
+	Role    string        `json:"role" validate:"enum=admin|user"`
+	Items   []bindItem    `json:"items" validate:"required"`
+	Even    int           `json:"even" validate:"even"`
+}
+
+func bindRequest(p *Prog, target string, body string, header map[string]string) (*httptest.ResponseRecorder, *bindInput) {
+	var in bindInput
+	p.Post("accounts/:id", func(c *Ctx) error {
+		err := c.Bind(&in)
+		if err != nil {
+			return err
+		}
+		c.NoContent()
+		return nil
+	})
+	return serveRequest(p, http.MethodPost, target, strings.NewReader(body), header), &in
+}
+
+func evenValidator(v reflect.Value, param string) error {
+	if v.Int()%2 != 0 {
+		return errors.New("must be even")
+	}
+	return nil
+}
+
+func TestBind(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Validator("even", evenValidator)
+	body := `{"email":"a@b.c","role":"admin","items":[{"name":"pen","qty":2}],"even":4}`
+	w, in := bindRequest(p, "/accounts/7?page=3&tag=a&tag=b&since=1h&account=6ba7b810-9dad-11d1-80b4-00c04fd430c8", body, map[string]string{"X-Token": "t"})
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal(7, in.ID)
+	assert.Equal(3, in.Page)
+	assert.Equal([]string{"a", "b"}, in.Tags)
+	assert.Equal(time.Hour, in.Since)
+	assert.Equal("6ba7b810-9dad-11d1-80b4-00c04fd430c8", in.Account.String())
+	assert.Equal("t", in.Token)
+	assert.Equal("pen", in.Items[0].Name)
+}
+
+func TestBindValidationErrors(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Validator("even", evenValidator)
+	body := `{"email":"invalid","role":"guest","items":[{"name":"pen","qty":1},{"name":"pencil","qty":0}],"even":3}`
+	w, _ := bindRequest(p, "/accounts/7?page=0", body, nil)
+	assert.Equal(http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(`{"errors":[
+		{"path":["page"],"message":"must be at least 1"},
+		{"path":["X-Token"],"message":"is required"},
+		{"path":["email"],"message":"must match ^[^@]+@[^@]+$"},
+		{"path":["role"],"message":"must be one of admin, user"},
+		{"path":["items","1","name"],"message":"must be at most 5 characters long"},
+		{"path":["items","1","qty"],"message":"must be at least 1"},
+		{"path":["even"],"message":"must be even"}
+	]}`, w.Body.String())
+}
+
+func TestBindInvalidValues(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	w, _ := bindRequest(p, "/accounts/abc?page=x", `{}`, nil)
+	assert.Equal(http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(`{"errors":[
+		{"path":["id"],"message":"invalid integer: abc"},
+		{"path":["page"],"message":"invalid integer: x"}
+	]}`, w.Body.String())
+	w, _ = bindRequest(New(), "/accounts/1", `{"email":`, nil)
+	assert.Equal(http.StatusBadRequest, w.Code)
+}
+
+func TestRequesterInputBind(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var got bindItem
+	p.Post("items", func(c *Ctx) error {
+		return c.Bind(&got)
+	})
+	var v interface{}
+	assert.NoError(p.Call().Create("items").Input(bindItem{Name: "pen", Qty: 1}).Bind(&v))
+	assert.Equal(bindItem{Name: "pen", Qty: 1}, got)
+	assert.NoError(p.Call().Create("items").Input(map[string]interface{}{"name": "cup", "qty": 2}).Bind(&v))
+	assert.Equal(bindItem{Name: "cup", Qty: 2}, got)
+}
+
+type bindForm struct {
+	Name   string                `json:"name" xml:"name" yaml:"name" validate:"required"`
+	Qty    int                   `json:"qty" xml:"qty" yaml:"qty"`
+	Tags   []string              `json:"tags" form:"tag" xml:"tag" yaml:"tags"`
+	Avatar *multipart.FileHeader `json:"-" form:"avatar"`
+}
+
+func bindFormRequest(p *Prog, contentType string, body io.Reader) (*httptest.ResponseRecorder, *bindForm) {
+	var in bindForm
+	p.Post("items", func(c *Ctx) error {
+		err := c.Bind(&in)
+		if err != nil {
+			return err
+		}
+		c.NoContent()
+		return nil
+	})
+	return serveRequest(p, http.MethodPost, "/items", body, map[string]string{"Content-Type": contentType}), &in
+}
+
+func TestBindContentTypes(t *testing.T) {
+	assert := assert.New(t)
+	want := bindForm{Name: "pen", Qty: 2, Tags: []string{"a", "b"}}
+
+	w, in := bindFormRequest(New(), "application/x-www-form-urlencoded", strings.NewReader("name=pen&qty=2&tag=a&tag=b"))
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal(want, *in)
+
+	w, in = bindFormRequest(New(), "application/xml; charset=utf-8", strings.NewReader("<item><name>pen</name><qty>2</qty><tag>a</tag><tag>b</tag></item>"))
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal(want, *in)
+
+	w, in = bindFormRequest(New(), "application/yaml", strings.NewReader("name: pen\nqty: 2\ntags: [a, b]\n"))
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal(want, *in)
+
+	w, _ = bindFormRequest(New(), "application/x-www-form-urlencoded", strings.NewReader("qty=x"))
+	assert.Equal(http.StatusBadRequest, w.Code)
+
+	w, _ = bindFormRequest(New(), "text/csv", strings.NewReader("name,qty"))
+	assert.Equal(http.StatusUnsupportedMediaType, w.Code)
+	assert.JSONEq(`{"message":"unsupported media type: text/csv"}`, w.Body.String())
+}
+
+func TestBindMultipart(t *testing.T) {
+	assert := assert.New(t)
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "pen")
+	mw.WriteField("tag", "a")
+	fw, _ := mw.CreateFormFile("avatar", "pen.png")
+	fw.Write([]byte("png"))
+	mw.Close()
+	w, in := bindFormRequest(New(), mw.FormDataContentType(), &body)
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal("pen", in.Name)
+	assert.Equal([]string{"a"}, in.Tags)
+	if assert.NotNil(in.Avatar) {
+		assert.Equal("pen.png", in.Avatar.Filename)
+		assert.Equal(int64(3), in.Avatar.Size)
+	}
+}
+
+func TestBindContentTypeFlag(t *testing.T) {
+	assert := assert.New(t)
+	u, _ := url.Parse("http://app.cli/items")
+	r := request.NewCLI(request.POST, u, strings.NewReader("name: pen\nqty: 2\n"))
+	r.Flags()[request.ContentTypeFlag] = []string{"yaml"}
+	var in bindForm
+	assert.NoError(r.Bind(&in))
+	assert.Equal(bindForm{Name: "pen", Qty: 2}, in)
+}
+
+func TestMultipartDecoderRemovesFiles(t *testing.T) {
+	assert := assert.New(t)
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "pen")
+	fw, _ := mw.CreateFormFile("avatar", "pen.png")
+	fw.Write(bytes.Repeat([]byte("x"), 1024))
+	mw.Close()
+	var in bindForm
+	err := request.MultipartDecoder{MaxMemory: 1}.Decode(&body, map[string]string{"boundary": mw.Boundary()}, &in)
+	assert.NoError(err)
+	assert.Equal("pen", in.Name)
+	if assert.NotNil(in.Avatar) {
+		assert.Equal(int64(1024), in.Avatar.Size)
+	}
+	files, err := os.ReadDir(tmp)
+	assert.NoError(err)
+	assert.Empty(files)
+}
+
+func TestBindMultipartMaxMemory(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.MaxMemory = 1
+	p.Post("items", func(c *Ctx) error {
+		var in bindForm
+		err := c.Bind(&in)
+		if err != nil {
+			return err
+		}
+		f, err := in.Avatar.Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, onDisk := f.(*os.File)
+		c.JSON(onDisk)
+		return nil
+	})
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "pen")
+	fw, _ := mw.CreateFormFile("avatar", "pen.png")
+	fw.Write([]byte("png"))
+	mw.Close()
+	w := serveRequest(p, http.MethodPost, "/items", &body, map[string]string{"Content-Type": mw.FormDataContentType()})
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{"data":true}`, w.Body.String())
+}
+
+func TestValidateInvalidTags(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	for _, v := range []interface{}{
+		&struct {
+			Name string `validate:"regex=("`
+		}{Name: "a"},
+		&struct {
+			Qty int `validate:"min=one"`
+		}{Qty: 1},
+		&struct {
+			Ok bool `validate:"max=1"`
+		}{},
+		&struct {
+			Items []struct {
+				Name string `validate:"odd"`
+			}
+		}{},
+	} {
+		errs, err := p.Validate(v)
+		assert.Error(err)
+		assert.Empty(errs)
+	}
+	var in struct {
+		Name string `json:"name" validate:"required,regex=["`
+	}
+	p.Post("items", func(c *Ctx) error {
+		return c.Bind(&in)
+	})
+	w := serveRequest(p, http.MethodPost, "/items", strings.NewReader(`{"name":"pen"}`), nil)
+	assert.Equal(http.StatusInternalServerError, w.Code)
+}
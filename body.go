@@ -0,0 +1,97 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/torniker/wrap/request"
+)
+
+// DefaultMaxBodySize is the default limit of request body size
+const DefaultMaxBodySize = 32 << 20
+
+// bodyLimiter is implemented by requests which can limit their body size
+type bodyLimiter interface {
+	LimitBody(n int64)
+}
+
+// multiparter is implemented by requests which can carry multipart body
+type multiparter interface {
+	MultipartReader() (*multipart.Reader, error)
+	MultipartForm(maxMemory int64) (*multipart.Form, error)
+}
+
+// memoryLimiter is implemented by requests which can bind multipart body
+type memoryLimiter interface {
+	SetMaxMemory(n int64)
+}
+
+// limitBody limits request body to n bytes, non positive n leaves the body unlimited
+func limitBody(ctx *Ctx, n int64) {
+	if l, ok := ctx.Request.(bodyLimiter); ok && n > 0 {
+		l.LimitBody(n)
+	}
+}
+
+// BodyLimit overrides Prog.MaxBodySize for the routes it is applied to
+func BodyLimit(n int64) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Ctx) error {
+			limitBody(ctx, n)
+			return next(ctx)
+		}
+	}
+}
+
+// Body returns raw request body for streaming, reading past the body limit returns request.ErrBodyTooLarge
+func (ctx *Ctx) Body() io.Reader {
+	if b := ctx.Request.Body(); b != nil {
+		return b
+	}
+	return http.NoBody
+}
+
+// MultipartReader returns reader of multipart parts which streams uploads without spooling them
+func (ctx *Ctx) MultipartReader() (*multipart.Reader, error) {
+	m, ok := ctx.Request.(multiparter)
+	if !ok {
+		return nil, ctx.UnsupportedMediaType(request.ErrNotMultipart.Error())
+	}
+	r, err := m.MultipartReader()
+	if err != nil {
+		return nil, ctx.bodyError(err)
+	}
+	return r, nil
+}
+
+// FormFile returns the first file uploaded in the field, files bigger than Prog.MaxMemory
+// are spooled to temporary files which are removed once the request is served
+func (ctx *Ctx) FormFile(name string) (*multipart.FileHeader, error) {
+	m, ok := ctx.Request.(multiparter)
+	if !ok {
+		return nil, ctx.UnsupportedMediaType(request.ErrNotMultipart.Error())
+	}
+	form, err := m.MultipartForm(ctx.Prog.MaxMemory)
+	if err != nil {
+		return nil, ctx.bodyError(err)
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, ctx.BadRequest(fmt.Sprintf("file %v is missing", name))
+	}
+	return files[0], nil
+}
+
+// bodyError converts error of reading request body into response error
+func (ctx *Ctx) bodyError(err error) error {
+	switch {
+	case errors.Is(err, request.ErrBodyTooLarge):
+		return ctx.PayloadTooLarge(err.Error())
+	case errors.Is(err, request.ErrUnsupportedMediaType), errors.Is(err, request.ErrNotMultipart):
+		return ctx.UnsupportedMediaType(err.Error())
+	}
+	return ctx.BadRequest(fmt.Sprintf("invalid body: %v", err))
+}
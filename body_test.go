@@ -0,0 +1,138 @@
+package wrap
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+	"github.com/torniker/wrap/response"
+)
+
+func TestBodyStream(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.MaxBodySize = 8
+	var got []byte
+	p.Post("upload", func(c *Ctx) error {
+		b, err := io.ReadAll(c.Body())
+		if err != nil {
+			return err
+		}
+		got = b
+		c.NoContent()
+		return nil
+	})
+	w := serveRequest(p, http.MethodPost, "/upload", strings.NewReader("12345678"), map[string]string{"Content-Type": "application/octet-stream"})
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal("12345678", string(got))
+
+	w = serveRequest(p, http.MethodPost, "/upload", strings.NewReader("123456789"), map[string]string{"Content-Type": "application/octet-stream"})
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(`{"message":"request body too large"}`, w.Body.String())
+}
+
+func TestBodyLimit(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.MaxBodySize = 4
+	var in bindItem
+	p.Post("upload", func(c *Ctx) error {
+		return c.Bind(&in)
+	}, BodyLimit(64))
+	w := serveRequest(p, http.MethodPost, "/upload", strings.NewReader(`{"name":"pen","qty":1}`), map[string]string{"Content-Type": "application/json"})
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("pen", in.Name)
+
+	p.Post("small", func(c *Ctx) error {
+		return c.Bind(&in)
+	}, BodyLimit(8))
+	w = serveRequest(p, http.MethodPost, "/small", strings.NewReader(`{"name":"pen","qty":1}`), nil)
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestFormFile(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.MaxMemory = 1
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "data.bin")
+	fw.Write(bytes.Repeat([]byte("x"), 1024))
+	mw.Close()
+	var got []byte
+	p.Post("upload", func(c *Ctx) error {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return err
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		got, _ = io.ReadAll(f)
+		_, err = c.FormFile("missing")
+		return err
+	})
+	w := serveRequest(p, http.MethodPost, "/upload", &body, map[string]string{"Content-Type": mw.FormDataContentType()})
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.Len(got, 1024)
+
+	w = serveRequest(p, http.MethodPost, "/upload", strings.NewReader("{}"), map[string]string{"Content-Type": "application/json"})
+	assert.Equal(http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestMultipartReader(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.MaxBodySize = 256
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "data.bin")
+	fw.Write(bytes.Repeat([]byte("x"), 512))
+	mw.Close()
+	p.Post("upload", func(c *Ctx) error {
+		mr, err := c.MultipartReader()
+		if err != nil {
+			return err
+		}
+		part, err := mr.NextPart()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(io.Discard, part)
+		return err
+	})
+	w := serveRequest(p, http.MethodPost, "/upload", &body, map[string]string{"Content-Type": mw.FormDataContentType()})
+	assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestCLIFileInput(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "item.json")
+	assert.NoError(os.WriteFile(path, []byte(`{"name":"pen","qty":2}`), 0600))
+	p := New()
+	var in bindItem
+	p.Post("items", func(c *Ctx) error {
+		return c.Bind(&in)
+	})
+	input, err := request.OpenInput("@" + path)
+	assert.NoError(err)
+	u, _ := url.Parse("http://app.cli/items")
+	ctx := p.NewCtx(request.NewCLI(request.POST, u, input), response.NewCLI())
+	p.serve(ctx)
+	assert.Equal(bindItem{Name: "pen", Qty: 2}, in)
+	_, err = input.(*os.File).Stat()
+	assert.Error(err, "file is closed once the request is served")
+
+	_, err = request.OpenInput("@" + filepath.Join(t.TempDir(), "missing"))
+	assert.Error(err)
+}
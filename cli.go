@@ -0,0 +1,502 @@
+package wrap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/torniker/wrap/request"
+	"github.com/torniker/wrap/response"
+)
+
+// StdinBody is the command body which reads the input from the following lines
+// up to a line with a single dot, or from stdin in one-shot mode
+const StdinBody = "-"
+
+// ErrIncomplete is returned by ParseCommand when quotes or JSON body are not closed
+// or the line ends with backslash, the command continues on the next line
+var ErrIncomplete = errors.New("incomplete command")
+
+// SyntaxError describes invalid command, Column is 0 for commands given as arguments
+type SyntaxError struct {
+	Column  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Column == 0 {
+		return "syntax error: " + e.Message
+	}
+	return fmt.Sprintf("syntax error at column %v: %v", e.Column, e.Message)
+}
+
+// Command is a parsed CLI command
+type Command struct {
+	Action request.Action
+	Path   string
+	Flags  map[string][]string
+	Header http.Header
+	// Body is JSON or text input, @path of a file or StdinBody
+	Body string
+}
+
+// ParseCommand parses command in format
+//
+//	[action] path[?query] [--flag[=value]...] [-H "Key: value"...] [body]
+//
+// Action defaults to GET. Words may be quoted with single or double quotes and escaped with backslash.
+// Body starting with { or [ is JSON which may span lines, @path streams the file and - reads stdin.
+func ParseCommand(line string) (*Command, error) {
+	return parseCommand(&scanner{s: line})
+}
+
+// parseCommand parses command read by sc, see ParseCommand
+func parseCommand(sc *scanner) (*Command, error) {
+	var words []cmdWord
+	for {
+		sc.skipSpace()
+		if sc.done() {
+			break
+		}
+		w := cmdWord{col: sc.pos + 1}
+		var err error
+		if c := sc.peek(); c == '{' || c == '[' {
+			w.json = true
+			w.text, err = sc.json()
+		} else {
+			w.text, err = sc.word()
+			w.literal = sc.literal
+		}
+		if err != nil {
+			return nil, err
+		}
+		w.end = sc.pos + 1
+		words = append(words, w)
+	}
+	if len(words) == 0 {
+		return nil, &SyntaxError{Column: 1, Message: "empty command"}
+	}
+	return newCommand(words)
+}
+
+// parseArgs parses command given as program arguments split by the shell
+func parseArgs(args []string) (*Command, error) {
+	if len(args) == 0 {
+		return nil, &SyntaxError{Message: "missing command"}
+	}
+	words := make([]cmdWord, len(args))
+	for i, arg := range args {
+		words[i] = cmdWord{text: arg}
+	}
+	return newCommand(words)
+}
+
+// cmdWord is a word of the command, col and end are columns of its start and end in the command line.
+// Literal words start with a variable value and are never taken for flags or headers.
+type cmdWord struct {
+	text    string
+	col     int
+	end     int
+	json    bool
+	literal bool
+}
+
+func newCommand(words []cmdWord) (*Command, error) {
+	cmd := &Command{
+		Action: request.NewActionFromString(words[0].text),
+		Path:   words[0].text,
+		Flags:  make(map[string][]string),
+		Header: make(http.Header),
+	}
+	if cmd.Action.IsValid() {
+		if len(words) == 1 {
+			return nil, &SyntaxError{Column: words[0].end, Message: "missing path"}
+		}
+		cmd.Path = words[1].text
+		words = words[2:]
+	} else {
+		cmd.Action = request.GET
+		words = words[1:]
+	}
+	hasBody := false
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		switch {
+		case w.json && hasBody:
+			return nil, &SyntaxError{Column: w.col, Message: "unexpected JSON, body is already given"}
+		case w.json:
+			cmd.Body = w.text
+			hasBody = true
+		case w.text == "-H" && !w.literal:
+			i++
+			if i == len(words) {
+				return nil, &SyntaxError{Column: w.end, Message: "missing header after -H"}
+			}
+			err := addHeader(cmd.Header, words[i].text, w.col)
+			if err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(w.text, "--header=") && !w.literal:
+			err := addHeader(cmd.Header, strings.TrimPrefix(w.text, "--header="), w.col)
+			if err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(w.text, "--") && len(w.text) > 2 && !w.literal:
+			kv := strings.SplitN(w.text[2:], "=", 2)
+			if len(kv) == 1 {
+				kv = append(kv, "")
+			}
+			cmd.Flags[kv[0]] = append(cmd.Flags[kv[0]], kv[1])
+		case !hasBody:
+			cmd.Body = w.text
+			hasBody = true
+		default:
+			return nil, &SyntaxError{Column: w.col, Message: fmt.Sprintf("unexpected argument %q", w.text)}
+		}
+	}
+	return cmd, nil
+}
+
+func addHeader(h http.Header, s string, col int) error {
+	kv := strings.SplitN(s, ":", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return &SyntaxError{Column: col, Message: fmt.Sprintf("invalid header %q, expected \"Key: value\"", s)}
+	}
+	h.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	return nil
+}
+
+// scanner splits command into shell-style words and JSON bodies. Variables are expanded by vars,
+// their values become part of the word they are in and JSON strings get them escaped.
+type scanner struct {
+	s   string
+	pos int
+	// vars returns value of variable reference at the start of the string and length of the reference,
+	// the length is 0 if there is none. Without vars the $ sign has no special meaning.
+	vars func(string) (interface{}, int, error)
+	// literal reports that the last word starts with a variable value
+	literal bool
+}
+
+func (sc *scanner) done() bool {
+	return sc.pos >= len(sc.s)
+}
+
+func (sc *scanner) peek() byte {
+	return sc.s[sc.pos]
+}
+
+func (sc *scanner) skipSpace() {
+	for !sc.done() && strings.IndexByte(" \t\r\n", sc.peek()) >= 0 {
+		sc.pos++
+	}
+}
+
+// variable writes value of variable reference at the current position before end, escaped by escape
+// if it is given, and reports whether there was a reference
+func (sc *scanner) variable(b *strings.Builder, end int, escape func(string) string) (bool, error) {
+	if sc.vars == nil || sc.peek() != '$' {
+		return false, nil
+	}
+	v, n, err := sc.vars(sc.s[sc.pos:end])
+	if err != nil || n == 0 {
+		return false, err
+	}
+	if b.Len() == 0 {
+		sc.literal = true
+	}
+	s := varString(v)
+	if escape != nil {
+		s = escape(s)
+	}
+	b.WriteString(s)
+	sc.pos += n
+	return true, nil
+}
+
+// word returns the next word with quotes removed, escapes resolved and variables expanded,
+// it is empty at the end
+func (sc *scanner) word() (string, error) {
+	sc.literal = false
+	sc.skipSpace()
+	var b strings.Builder
+	for !sc.done() {
+		c := sc.peek()
+		switch {
+		case strings.IndexByte(" \t\r\n", c) >= 0:
+			return b.String(), nil
+		case c == '\\':
+			sc.pos++
+			if sc.done() {
+				return "", ErrIncomplete
+			}
+			if sc.peek() != '\n' {
+				b.WriteByte(sc.peek())
+			}
+			sc.pos++
+		case c == '\'':
+			end := strings.IndexByte(sc.s[sc.pos+1:], '\'')
+			if end < 0 {
+				return "", ErrIncomplete
+			}
+			end += sc.pos + 1
+			for sc.pos++; sc.pos < end; {
+				if sc.vars != nil && strings.HasPrefix(sc.s[sc.pos:end], "\\$") {
+					b.WriteByte('$')
+					sc.pos += 2
+					continue
+				}
+				ok, err := sc.variable(&b, end, nil)
+				if err != nil {
+					return "", err
+				}
+				if !ok {
+					b.WriteByte(sc.peek())
+					sc.pos++
+				}
+			}
+			sc.pos++
+		case c == '"':
+			sc.pos++
+			for {
+				if sc.done() {
+					return "", ErrIncomplete
+				}
+				ok, err := sc.variable(&b, len(sc.s), nil)
+				if err != nil {
+					return "", err
+				}
+				if ok {
+					continue
+				}
+				c = sc.peek()
+				sc.pos++
+				if c == '"' {
+					break
+				}
+				if c == '\\' && !sc.done() && strings.IndexByte("\"\\$`", sc.peek()) >= 0 {
+					c = sc.peek()
+					sc.pos++
+				}
+				b.WriteByte(c)
+			}
+		default:
+			ok, err := sc.variable(&b, len(sc.s), nil)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				b.WriteByte(c)
+				sc.pos++
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// json returns JSON value starting at the current position up to its closing bracket with variables
+// expanded, values in strings are escaped and other values are inserted as they are
+func (sc *scanner) json() (string, error) {
+	var b strings.Builder
+	depth := 0
+	inString := false
+	for !sc.done() {
+		if sc.vars != nil && strings.HasPrefix(sc.s[sc.pos:], "\\$") {
+			b.WriteByte('$')
+			sc.pos += 2
+			continue
+		}
+		var escape func(string) string
+		if inString {
+			escape = jsonEscape
+		}
+		ok, err := sc.variable(&b, len(sc.s), escape)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			continue
+		}
+		c := sc.peek()
+		b.WriteByte(c)
+		sc.pos++
+		if inString {
+			switch c {
+			case '\\':
+				if !sc.done() {
+					b.WriteByte(sc.peek())
+					sc.pos++
+				}
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return b.String(), nil
+			}
+		}
+	}
+	return "", ErrIncomplete
+}
+
+// jsonEscape escapes s for JSON string
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
+
+// ListenCLI reads commands from buffer until exit command, end of input or shutdown.
+// Commands are parsed by ParseCommand, incomplete ones continue on the next line.
+func (p *Prog) ListenCLI(buf *bufio.ReadWriter) error {
+	s := p.newSession(os.Stdout)
+	var stdinErr error
+	s.stdin = func() io.Reader {
+		var r io.Reader
+		r, stdinErr = readUntilDot(buf.Reader)
+		return r
+	}
+	return s.run(func() (string, error) {
+		if stdinErr != nil {
+			return "", stdinErr
+		}
+		return buf.ReadString('\n')
+	}, func(prompt string) {
+		fmt.Print(prompt)
+	})
+}
+
+// Exit codes returned by RunArgs
+const (
+	ExitOK          = 0
+	ExitFailure     = 1 // the command could not run, e.g. start hook failed or input file is missing
+	ExitUsage       = 2 // the command is invalid
+	ExitRedirect    = 3 // 3xx response
+	ExitClientError = 4 // 4xx response
+	ExitServerError = 5 // 5xx response
+)
+
+// ExitCode returns exit code of the response status
+func ExitCode(status int) int {
+	switch {
+	case status >= 200 && status < 300:
+		return ExitOK
+	case status >= 300 && status < 400:
+		return ExitRedirect
+	case status >= 400 && status < 500:
+		return ExitClientError
+	case status >= 500:
+		return ExitServerError
+	}
+	return ExitFailure
+}
+
+// RunArgs runs single command given as program arguments, e.g. os.Args, prints the response to stdout
+// and returns exit code of its status. The app is started and shut down around the command
+// and body - is read from stdin.
+//
+//	os.Exit(p.RunArgs(os.Args))
+func (p *Prog) RunArgs(args []string) int {
+	return p.runArgs(args, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func (p *Prog) runArgs(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	name := "app"
+	if len(args) > 0 {
+		name = filepath.Base(args[0])
+		args = args[1:]
+	}
+	cmd, err := parseArgs(args)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		fmt.Fprintf(stderr, "usage: %v [action] path[?query] [--flag[=value]...] [-H \"Key: value\"...] [body]\n", name)
+		return ExitUsage
+	}
+	err = p.start(context.Background())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), p.DrainTimeout)
+		defer cancel()
+		err := p.Shutdown(ctx)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+		}
+	}()
+	ctx, err := p.runCommand(cmd, stdin, stdout, nil)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitFailure
+	}
+	return ExitCode(ctx.Response.Status())
+}
+
+// readUntilDot reads lines up to a line with a single dot
+func readUntilDot(r interface{ ReadString(byte) (string, error) }) (io.Reader, error) {
+	var b strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if strings.TrimRight(line, "\r\n") == "." {
+			return strings.NewReader(b.String()), nil
+		}
+		b.WriteString(line)
+		if err != nil {
+			return strings.NewReader(b.String()), err
+		}
+	}
+}
+
+// runCommand dispatches the command through DefaultHandler, the response is written to out or stdout if out is nil
+func (p *Prog) runCommand(cmd *Command, stdin io.Reader, out io.Writer, con *console) (*Ctx, error) {
+	u, err := url.Parse("http://app.cli/" + strings.TrimPrefix(cmd.Path, "/"))
+	if err != nil {
+		return nil, err
+	}
+	var input io.Reader
+	if cmd.Body == StdinBody {
+		input = stdin
+	} else {
+		input, err = request.OpenInput(cmd.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	req := request.NewCLI(cmd.Action, u, input).SetFlags(cmd.Flags)
+	if len(cmd.Header) > 0 {
+		req.SetHeader(cmd.Header)
+	}
+	res := response.NewCLI()
+	if out != nil {
+		res.SetOutput(out)
+	}
+	ctx := p.NewCtx(req, res)
+	ctx.console = con
+	closeOutput, err := cliOutput(ctx)
+	if err != nil {
+		req.Close()
+		return nil, err
+	}
+	defer closeOutput()
+	var cancel context.CancelFunc
+	ctx.context, cancel = context.WithCancel(ctx.context)
+	defer cancel()
+	p.serve(ctx)
+	return ctx, nil
+}
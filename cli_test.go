@@ -0,0 +1,119 @@
+package wrap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+)
+
+func TestParseCommand(t *testing.T) {
+	assert := assert.New(t)
+	cmd, err := ParseCommand(`post users?limit=10 --name="John Doe" --dry -H 'X-Token: a b' {"name": "it's \"quoted\" }"}`)
+	assert.NoError(err)
+	assert.Equal(request.POST, cmd.Action)
+	assert.Equal("users?limit=10", cmd.Path)
+	assert.Equal(map[string][]string{"name": {"John Doe"}, "dry": {""}}, cmd.Flags)
+	assert.Equal("a b", cmd.Header.Get("X-Token"))
+	assert.Equal(`{"name": "it's \"quoted\" }"}`, cmd.Body)
+
+	cmd, err = ParseCommand(`users/1 @data\ file.json --v=1`)
+	assert.NoError(err)
+	assert.Equal(request.GET, cmd.Action)
+	assert.Equal("users/1", cmd.Path)
+	assert.Equal("@data file.json", cmd.Body)
+	assert.Equal([]string{"1"}, cmd.Flags["v"])
+
+	_, err = ParseCommand("post users {\"name\":\n")
+	assert.Equal(ErrIncomplete, err)
+	_, err = ParseCommand(`get "users`)
+	assert.Equal(ErrIncomplete, err)
+	_, err = ParseCommand("post")
+	assert.EqualError(err, "syntax error at column 5: missing path")
+	_, err = ParseCommand("get users a b")
+	assert.EqualError(err, `syntax error at column 13: unexpected argument "b"`)
+	_, err = ParseCommand("get users -H nocolon")
+	assert.EqualError(err, `syntax error at column 11: invalid header "nocolon", expected "Key: value"`)
+}
+
+func TestListenCLI(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var got []string
+	p.Post("users", func(c *Ctx) error {
+		b, err := io.ReadAll(c.Body())
+		if err != nil {
+			return err
+		}
+		got = append(got, c.Flag("role")+" "+c.Header("X-Token")+" "+string(b))
+		return nil
+	})
+	in := "post users --role=admin -H 'X-Token: t' {\n  \"name\": \"a\"\n}\n" +
+		"# comment\n" +
+		"post users?role=user -\nline 1\nline 2\n.\n" +
+		"exit\n"
+	buf := bufio.NewReadWriter(bufio.NewReader(strings.NewReader(in)), bufio.NewWriter(io.Discard))
+	assert.NoError(p.ListenCLI(buf))
+	assert.Equal([]string{"admin t {\n  \"name\": \"a\"\n}", "user  line 1\nline 2\n"}, got)
+}
+
+func TestRunCommand(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("users", func(c *Ctx) error {
+		return c.JSON(map[string]string{"limit": c.Flag("limit")})
+	})
+	cmd, err := ParseCommand("users?limit=1 --limit=2")
+	assert.NoError(err)
+	var out bytes.Buffer
+	ctx, err := p.runCommand(cmd, nil, &out, nil)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, ctx.Response.Status())
+	assert.JSONEq(`{"data":{"limit":"2"}}`, out.String())
+}
+
+func TestRunArgs(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var shutdown bool
+	p.OnShutdown(func(ctx context.Context) error {
+		shutdown = true
+		return nil
+	})
+	p.Post("users", func(c *Ctx) error {
+		var in struct {
+			Name string `json:"name"`
+		}
+		err := c.Bind(&in)
+		if err != nil {
+			return err
+		}
+		return c.JSON(map[string]string{"name": in.Name, "role": c.Flag("role")})
+	})
+	p.Get("fail", func(c *Ctx) error {
+		return c.InternalServerError(errors.New("boom"))
+	})
+	p.Post("seed", func(c *Ctx) error {
+		return nil
+	})
+	var stdout, stderr bytes.Buffer
+	code := p.runArgs([]string{"app", "post", "users", "--role=admin", "-"}, strings.NewReader(`{"name":"John Doe"}`), &stdout, &stderr)
+	assert.Equal(ExitOK, code)
+	assert.JSONEq(`{"data":{"name":"John Doe","role":"admin"}}`, stdout.String())
+	assert.True(shutdown)
+
+	assert.Equal(ExitOK, p.runArgs([]string{"app", "post", "seed"}, nil, io.Discard, io.Discard))
+	assert.Equal(ExitClientError, p.runArgs([]string{"app", "missing"}, nil, io.Discard, io.Discard))
+	assert.Equal(ExitServerError, p.runArgs([]string{"app", "fail"}, nil, io.Discard, io.Discard))
+	stderr.Reset()
+	assert.Equal(ExitUsage, p.runArgs([]string{"app", "post"}, nil, io.Discard, &stderr))
+	assert.Equal("syntax error: missing path\nusage: app [action] path[?query] [--flag[=value]...] [-H \"Key: value\"...] [body]\n", stderr.String())
+	assert.Equal(ExitFailure, p.runArgs([]string{"app", "post", "users", "@missing.json"}, nil, io.Discard, io.Discard))
+}
@@ -1,6 +1,11 @@
 package wrap
 
 import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/torniker/wrap/logger"
 	"github.com/torniker/wrap/request"
 	"github.com/torniker/wrap/response"
 )
@@ -15,6 +20,91 @@ type Ctx struct {
 	Response response.Response
 	Store    map[string]interface{}
 	User     Userer
+	conn     *WSConn
+	context  context.Context
+	id       string
+	logger   *logger.Logger
+	format   string
+	console  *console
+	// batchDepth is the number of batches the request runs in
+	batchDepth int
+}
+
+// console replaces outputs of the app for requests of a transport which writes to the terminal or stdout
+// itself, e.g. REPL or stdio, so that log entries do not break its output
+type console struct {
+	// logger replaces Prog.Logger, nil keeps it
+	logger *logger.Logger
+	// stdout and stderr replace os.Stdout and os.Stderr given as access log output, nil keeps them
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// newConsole returns console which moves the default logger and access log written to os.Stdout
+// to stdout, access log written to os.Stderr is moved to stderr if it is not nil
+func (p *Prog) newConsole(stdout, stderr io.Writer) *console {
+	c := &console{
+		stdout: stdout,
+		stderr: stderr,
+	}
+	if p.Logger == logger.Default() {
+		c.logger = p.Logger.WithSinks(logger.NewSink(stdout, logger.TextEncoder{}))
+	}
+	return c
+}
+
+// writer returns replacement of w
+func (c *console) writer(w io.Writer) io.Writer {
+	switch {
+	case w == io.Writer(os.Stdout) && c.stdout != nil:
+		return c.stdout
+	case w == io.Writer(os.Stderr) && c.stderr != nil:
+		return c.stderr
+	}
+	return w
+}
+
+// RequestID returns unique id of the request
+func (ctx *Ctx) RequestID() string {
+	return ctx.id
+}
+
+// Logger returns logger of the app with request id, action, path and user id attached
+func (ctx *Ctx) Logger() *logger.Logger {
+	if ctx.logger == nil {
+		ctx.logger = ctx.appLogger().With(
+			"request_id", ctx.id,
+			"action", ctx.Request.Action().String(),
+			"path", ctx.Request.Path().URL().Path,
+		)
+	}
+	if ctx.User != nil {
+		return ctx.logger.With("user_id", ctx.User.ID())
+	}
+	return ctx.logger
+}
+
+// appLogger returns logger of the app replaced by the console of the transport
+func (ctx *Ctx) appLogger() *logger.Logger {
+	if ctx.console != nil && ctx.console.logger != nil {
+		return ctx.console.logger
+	}
+	return ctx.Prog.Logger
+}
+
+// Context returns context of the request.
+// For http it is the context of http request, for cli and websocket it is canceled
+// when the command is done or the connection is closed, sub requests inherit context of the caller.
+func (ctx *Ctx) Context() context.Context {
+	if ctx.context == nil {
+		return context.Background()
+	}
+	return ctx.context
+}
+
+// SetContext replaces context of the request
+func (ctx *Ctx) SetContext(c context.Context) {
+	ctx.context = c
 }
 
 // Post handles checks if the request method and calls HandlerFunc
@@ -38,6 +128,20 @@ func (ctx *Ctx) Put(f HandlerFunc) {
 	}
 }
 
+// Patch handles checks if the request method and calls HandlerFunc
+func (ctx *Ctx) Patch(f HandlerFunc) {
+	if ctx.Request.Action() == request.PATCH {
+		ctx.call(f)
+	}
+}
+
+// Head handles checks if the request method and calls HandlerFunc
+func (ctx *Ctx) Head(f HandlerFunc) {
+	if ctx.Request.Action() == request.HEAD {
+		ctx.call(f)
+	}
+}
+
 // Delete handles checks if the request method and calls HandlerFunc
 func (ctx *Ctx) Delete(f HandlerFunc) {
 	if ctx.Request.Action() == request.DELETE {
@@ -49,6 +153,14 @@ func (ctx *Ctx) call(f HandlerFunc) {
 	if ctx.Response.Commited() {
 		return
 	}
+	defer func() {
+		if v := recover(); v != nil {
+			err := ctx.Prog.recovered(ctx, v)
+			if !ctx.Response.Commited() {
+				ctx.Error(err)
+			}
+		}
+	}()
 	err := f(ctx)
 	if err != nil {
 		ctx.Error(err)
@@ -61,15 +173,23 @@ func (ctx *Ctx) Next(f HandlerFunc) {
 	ctx.call(f)
 }
 
-// ResJSON is a struct for wrapping a JSON data response
+// Param returns value of the named path param matched by the router
+func (ctx *Ctx) Param(name string) string {
+	return ctx.Request.Params()[name]
+}
+
+// ResJSON is a struct for wrapping a data response
 type ResJSON struct {
-	Data interface{} `json:"data"`
+	Data interface{} `json:"data" xml:"data" yaml:"data" msgpack:"data"`
 }
 
-// JSON responses with json body
+// JSON responses with json body wrapped into the envelope of json format
 func (ctx *Ctx) JSON(body interface{}) error {
-	ctx.Response.SetHeader("Content-Type", "application/json")
-	return ctx.Response.Write(ResJSON{Data: body})
+	f := ctx.Prog.format(FormatJSON)
+	if f == nil {
+		f = jsonFormat
+	}
+	return ctx.encode(f, body)
 }
 
 // NoContent responses with status 204 No Content
@@ -116,3 +236,19 @@ func (ctx *Ctx) IsOPTIONS() bool {
 	}
 	return false
 }
+
+// IsPATCH handles checks if the request method is PATCH
+func (ctx *Ctx) IsPATCH() bool {
+	if ctx.Request.Action() == request.PATCH {
+		return true
+	}
+	return false
+}
+
+// IsHEAD handles checks if the request method is HEAD
+func (ctx *Ctx) IsHEAD() bool {
+	if ctx.Request.Action() == request.HEAD {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package wrap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+)
+
+type ctxKey struct{}
+
+func TestContextHTTP(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("ctx", func(c *Ctx) error {
+		return c.JSON(c.Context().Value(ctxKey{}))
+	})
+	r := httptest.NewRequest(http.MethodGet, "/ctx", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, "http"))
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.JSONEq(`{"data":"http"}`, w.Body.String())
+}
+
+func TestContextSubRequest(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var sub interface{}
+	p.Get("inner", func(c *Ctx) error {
+		sub = c.Context().Value(ctxKey{})
+		return c.JSON("inner")
+	})
+	p.Get("outer", func(c *Ctx) error {
+		c.SetContext(context.WithValue(c.Context(), ctxKey{}, "parent"))
+		var v interface{}
+		return c.Call().Read("inner").Bind(&v)
+	})
+	serve(p, request.GET, "outer")
+	assert.Equal("parent", sub)
+}
+
+func TestContextTimeout(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("slow", func(c *Ctx) error {
+		select {
+		case <-time.After(time.Second):
+			return c.JSON("done")
+		case <-c.Context().Done():
+			return c.Context().Err()
+		}
+	}, Timeout(10*time.Millisecond))
+	p.Get("ignores", func(c *Ctx) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}, Timeout(10*time.Millisecond))
+	ctx := serve(p, request.GET, "slow")
+	assert.Equal(http.StatusServiceUnavailable, ctx.Response.Status())
+	ctx = serve(p, request.GET, "ignores")
+	assert.Equal(http.StatusServiceUnavailable, ctx.Response.Status())
+}
+
+func TestContextCanceledOnShutdown(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	ctx := serve(p, request.GET, "missing")
+	assert.NoError(ctx.Context().Err())
+	assert.NoError(p.Shutdown(context.Background()))
+	assert.Equal(context.Canceled, ctx.Context().Err())
+}
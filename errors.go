@@ -1,44 +1,67 @@
 package wrap
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
-	"github.com/torniker/wrap/logger"
+	"github.com/torniker/wrap/request"
 )
 
-// Error checks error type and responses accordingly
+// Error checks error type and responses accordingly, the body is encoded
+// in the negotiated format or in the default one if none is acceptable
 func (ctx *Ctx) Error(err error) {
-	switch err.(type) {
+	if ctx.Response.Commited() {
+		// status and body of e.g. a stream are sent already
+		ctx.Logger().Error("error after response was committed", "error", err)
+		return
+	}
+	status, body := errorResponse(err)
+	f, e := ctx.negotiate()
+	if e != nil {
+		f = ctx.Prog.defaultFormat()
+	}
+	ctx.Response.SetHeader("Content-Type", f.MediaType)
+	ctx.Response.SetEncoder(f.Encoder)
+	ctx.Response.SetStatus(status)
+	ctx.Response.Write(body)
+}
+
+// errorResponse returns response status and body for the error
+func errorResponse(err error) (int, interface{}) {
+	switch e := err.(type) {
 	case ErrorBadRequest:
-		ctx.Response.SetStatus(http.StatusBadRequest)
-		e := err.(ErrorBadRequest)
-		ctx.Response.Write(e)
+		return http.StatusBadRequest, e
 	case ErrorUnauthorized:
-		ctx.Response.SetStatus(http.StatusUnauthorized)
-		e := err.(ErrorUnauthorized)
-		ctx.Response.Write(e)
+		return http.StatusUnauthorized, e
 	case ErrorMethodNotAllowed:
-		ctx.Response.SetStatus(http.StatusMethodNotAllowed)
-		e := err.(ErrorMethodNotAllowed)
-		ctx.Response.Write(e)
+		return http.StatusMethodNotAllowed, e
 	case ErrorStatusNotFound:
-		ctx.Response.SetStatus(http.StatusNotFound)
-		e := err.(ErrorStatusNotFound)
-		ctx.Response.Write(e)
+		return http.StatusNotFound, e
+	case ErrorNotAcceptable:
+		return http.StatusNotAcceptable, e
+	case ErrorPayloadTooLarge:
+		return http.StatusRequestEntityTooLarge, e
+	case ErrorUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType, e
 	case ErrorInternalServerError:
-		ctx.Response.SetStatus(http.StatusInternalServerError)
-		e := err.(ErrorInternalServerError)
-		ctx.Response.Write(e)
+		return http.StatusInternalServerError, e
 	case ErrorUnprocessableEntity:
-		ctx.Response.SetStatus(http.StatusUnprocessableEntity)
-		e := err.(ErrorUnprocessableEntity)
-		ctx.Response.Write(e)
-	default:
-		ctx.Response.SetStatus(http.StatusInternalServerError)
-		ctx.Response.Write(ErrorInternalServerError{Message: err.Error()})
+		return http.StatusUnprocessableEntity, e
+	case ErrorServiceUnavailable:
+		return http.StatusServiceUnavailable, e
+	case ErrorStatus:
+		return e.Status, e
 	}
+	if errors.Is(err, request.ErrBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge, ErrorPayloadTooLarge{Message: err.Error()}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable, ErrorServiceUnavailable{Message: "request timeout"}
+	}
+	return http.StatusInternalServerError, ErrorInternalServerError{Message: err.Error()}
 }
 
 // NotFound response 404
@@ -47,7 +70,7 @@ func (ctx *Ctx) NotFound() error {
 		Message:  "not found",
 		Internal: fmt.Sprintf("url: %v not found", ctx.Request.Path().URL().Path),
 	}
-	logger.Error(e.Internal)
+	ctx.Logger().Error(e.Internal)
 	return e
 }
 
@@ -57,7 +80,7 @@ func (ctx *Ctx) Unauthorized() error {
 		Message:  "unauthorized",
 		Internal: fmt.Sprintf("user: %v is unauthorized to request: %v", ctx.User, ctx.Request.Path().URL().Path),
 	}
-	logger.Error(e.Internal)
+	ctx.Logger().Error(e.Internal)
 	return e
 }
 
@@ -67,7 +90,7 @@ func (ctx *Ctx) InternalServerError(err error) error {
 		Message:  "internal server error",
 		Internal: err.Error(),
 	}
-	logger.Error(e.Internal)
+	ctx.Logger().Error(e.Internal)
 	return e
 }
 
@@ -77,7 +100,7 @@ func (ctx *Ctx) BadRequest(message string) error {
 		Message:  message,
 		Internal: fmt.Sprintf("bad request: %#v, message: %v", ctx.Request, message),
 	}
-	logger.Error(e.Internal)
+	ctx.Logger().Error(e.Internal)
 	return e
 }
 
@@ -87,7 +110,37 @@ func (ctx *Ctx) MethodNotAllowed() error {
 		Message:  "method not allowed",
 		Internal: fmt.Sprintf("user: %v is not allowed to request: %v", ctx.User, ctx.Request.Path().URL().Path),
 	}
-	logger.Error(e.Internal)
+	ctx.Logger().Error(e.Internal)
+	return e
+}
+
+// NotAcceptable response 406
+func (ctx *Ctx) NotAcceptable(message string) error {
+	e := ErrorNotAcceptable{
+		Message:  message,
+		Internal: fmt.Sprintf("not acceptable: %v, message: %v", ctx.Request.Path().URL().Path, message),
+	}
+	ctx.Logger().Error(e.Internal)
+	return e
+}
+
+// PayloadTooLarge response 413
+func (ctx *Ctx) PayloadTooLarge(message string) error {
+	e := ErrorPayloadTooLarge{
+		Message:  message,
+		Internal: fmt.Sprintf("payload too large: %v, message: %v", ctx.Request.Path().URL().Path, message),
+	}
+	ctx.Logger().Error(e.Internal)
+	return e
+}
+
+// UnsupportedMediaType response 415
+func (ctx *Ctx) UnsupportedMediaType(message string) error {
+	e := ErrorUnsupportedMediaType{
+		Message:  message,
+		Internal: fmt.Sprintf("unsupported media type: %v, message: %v", ctx.Request.Path().URL().Path, message),
+	}
+	ctx.Logger().Error(e.Internal)
 	return e
 }
 
@@ -97,7 +150,17 @@ func (ctx *Ctx) UnprocessableEntity(errors FieldErrors) error {
 		Errors:   errors,
 		Internal: fmt.Sprintf("UnprocessableEntity errors: %v ", errors.String()),
 	}
-	logger.Error(e.Internal)
+	ctx.Logger().Error(e.Internal)
+	return e
+}
+
+// ServiceUnavailable response 503
+func (ctx *Ctx) ServiceUnavailable(message string) error {
+	e := ErrorServiceUnavailable{
+		Message:  message,
+		Internal: fmt.Sprintf("service unavailable: %v, message: %v", ctx.Request.Path().URL().Path, message),
+	}
+	ctx.Logger().Error(e.Internal)
 	return e
 }
 
@@ -141,6 +204,36 @@ func (e ErrorStatusNotFound) Error() string {
 	return e.Message
 }
 
+// ErrorNotAcceptable type for not acceptable
+type ErrorNotAcceptable struct {
+	Message  string `json:"message"`
+	Internal string `json:"-"`
+}
+
+func (e ErrorNotAcceptable) Error() string {
+	return e.Message
+}
+
+// ErrorPayloadTooLarge type for payload too large
+type ErrorPayloadTooLarge struct {
+	Message  string `json:"message"`
+	Internal string `json:"-"`
+}
+
+func (e ErrorPayloadTooLarge) Error() string {
+	return e.Message
+}
+
+// ErrorUnsupportedMediaType type for unsupported media type
+type ErrorUnsupportedMediaType struct {
+	Message  string `json:"message"`
+	Internal string `json:"-"`
+}
+
+func (e ErrorUnsupportedMediaType) Error() string {
+	return e.Message
+}
+
 // ErrorInternalServerError type for internal server error
 type ErrorInternalServerError struct {
 	Message  string `json:"message"`
@@ -151,6 +244,16 @@ func (e ErrorInternalServerError) Error() string {
 	return e.Message
 }
 
+// ErrorServiceUnavailable type for service unavailable
+type ErrorServiceUnavailable struct {
+	Message  string `json:"message"`
+	Internal string `json:"-"`
+}
+
+func (e ErrorServiceUnavailable) Error() string {
+	return e.Message
+}
+
 // FieldError describes error per field
 type FieldError struct {
 	Path    []string `json:"path"`
@@ -181,3 +284,14 @@ type ErrorUnprocessableEntity struct {
 func (e ErrorUnprocessableEntity) Error() string {
 	return e.Errors.String()
 }
+
+// ErrorStatus type for responses with status which has no dedicated error type
+type ErrorStatus struct {
+	Status   int    `json:"-"`
+	Message  string `json:"message"`
+	Internal string `json:"-"`
+}
+
+func (e ErrorStatus) Error() string {
+	return e.Message
+}
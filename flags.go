@@ -0,0 +1,92 @@
+package wrap
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Flag returns the first value of the flag, flags of HTTP requests are query params
+func (ctx *Ctx) Flag(name string) string {
+	if vals := ctx.Request.Flags()[name]; len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+// FlagInt returns the flag as int, missing flag returns 0 and invalid one ErrorBadRequest
+func (ctx *Ctx) FlagInt(name string) (int, error) {
+	s := ctx.Flag(name)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, ctx.invalidFlag(name, s)
+	}
+	return n, nil
+}
+
+// FlagBool returns the flag as bool, flag given without value is true
+func (ctx *Ctx) FlagBool(name string) (bool, error) {
+	vals, ok := ctx.Request.Flags()[name]
+	if !ok {
+		return false, nil
+	}
+	if len(vals) == 0 || vals[0] == "" {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(vals[0])
+	if err != nil {
+		return false, ctx.invalidFlag(name, vals[0])
+	}
+	return b, nil
+}
+
+// FlagTime returns the flag parsed in RFC 3339 format, missing flag returns zero time
+func (ctx *Ctx) FlagTime(name string) (time.Time, error) {
+	s := ctx.Flag(name)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, ctx.invalidFlag(name, s)
+	}
+	return t, nil
+}
+
+// FlagUUID returns the flag as UUID, missing flag returns uuid.Nil
+func (ctx *Ctx) FlagUUID(name string) (uuid.UUID, error) {
+	s := ctx.Flag(name)
+	if s == "" {
+		return uuid.Nil, nil
+	}
+	id, err := uuid.FromString(s)
+	if err != nil {
+		return uuid.Nil, ctx.invalidFlag(name, s)
+	}
+	return id, nil
+}
+
+func (ctx *Ctx) invalidFlag(name, val string) error {
+	return ctx.BadRequest(fmt.Sprintf("invalid value of %v flag: %v", name, val))
+}
+
+// Header returns the first value of the request header
+func (ctx *Ctx) Header(name string) string {
+	return ctx.Request.Header().Get(name)
+}
+
+// Cookie returns the named request cookie or nil if there is none
+func (ctx *Ctx) Cookie(name string) *http.Cookie {
+	for _, c := range ctx.Request.Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
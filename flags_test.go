@@ -0,0 +1,95 @@
+package wrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+	"github.com/torniker/wrap/response"
+)
+
+func TestFlags(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var got struct {
+		flags   map[string][]string
+		limit   int
+		verbose bool
+		since   time.Time
+		session string
+		input   struct {
+			Session string `json:"-" cookie:"session"`
+		}
+	}
+	p.Get("users", func(c *Ctx) error {
+		var err error
+		got.flags = c.Request.Flags()
+		got.limit, err = c.FlagInt("limit")
+		if err != nil {
+			return err
+		}
+		got.verbose, err = c.FlagBool("verbose")
+		if err != nil {
+			return err
+		}
+		got.since, err = c.FlagTime("since")
+		if err != nil {
+			return err
+		}
+		_, err = c.FlagUUID("account")
+		if err != nil {
+			return err
+		}
+		if cookie := c.Cookie("session"); cookie != nil {
+			got.session = cookie.Value
+		}
+		return c.Bind(&got.input)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/users?limit=10&verbose&since=2020-01-02T03:04:05Z", nil)
+	r.Header.Set("limit", "99")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal(map[string][]string{"limit": {"10"}, "verbose": {""}, "since": {"2020-01-02T03:04:05Z"}}, got.flags)
+	assert.Equal(10, got.limit)
+	assert.True(got.verbose)
+	assert.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), got.since)
+	assert.Equal("s1", got.session)
+	assert.Equal("s1", got.input.Session)
+
+	for _, query := range []string{"limit=x", "verbose=maybe", "since=yesterday", "account=42"} {
+		w = httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?"+query, nil))
+		assert.Equal(http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestFlagsCLI(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var limit int
+	var token string
+	p.Get("users/:id", func(c *Ctx) error {
+		limit, _ = c.FlagInt("limit")
+		token = c.Header("X-Token")
+		return c.JSON(c.Param("id"))
+	})
+	u, _ := url.Parse("http://app.cli/users/7?limit=5")
+	req := request.NewCLI(request.GET, u, nil)
+	req.SetHeader(http.Header{"X-Token": {"t"}})
+	ctx := p.NewCtx(req, response.NewResponse())
+	assert.NoError(p.handle(ctx))
+	assert.Equal(5, limit)
+	assert.Equal("t", token)
+	assert.Equal(map[string]string{"id": "7"}, ctx.Request.Params())
+
+	u, _ = url.Parse("users/7?limit=5")
+	sub := request.NewRequest(request.GET, u, nil).SetFlags(map[string][]string{"limit": {"6"}})
+	assert.NoError(p.handle(p.NewCtx(sub, response.NewResponse())))
+	assert.Equal(6, limit)
+}
@@ -0,0 +1,219 @@
+package wrap
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/torniker/wrap/response"
+)
+
+// FormatFlag is the flag which selects response format by name, e.g. --format=yaml or ?format=yaml
+const FormatFlag = "format"
+
+// list of built in format names
+const (
+	FormatJSON    = "json"
+	FormatXML     = "xml"
+	FormatYAML    = "yaml"
+	FormatMsgpack = "msgpack"
+	FormatCSV     = "csv"
+	FormatText    = "text"
+)
+
+// Envelope wraps response body before it is encoded
+type Envelope func(body interface{}) interface{}
+
+// DataEnvelope wraps body into ResJSON
+func DataEnvelope(body interface{}) interface{} {
+	return ResJSON{Data: body}
+}
+
+// Format describes how response body is encoded for the media type
+type Format struct {
+	Name      string
+	MediaType string
+	Aliases   []string
+	Encoder   response.Encoder
+	Envelope  Envelope
+}
+
+func (f *Format) matches(mediaType string) bool {
+	if strings.EqualFold(f.MediaType, mediaType) {
+		return true
+	}
+	for _, a := range f.Aliases {
+		if strings.EqualFold(a, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+var jsonFormat = &Format{
+	Name:      FormatJSON,
+	MediaType: "application/json",
+	Encoder:   response.JSONEncoder{},
+	Envelope:  DataEnvelope,
+}
+
+func defaultFormats() []*Format {
+	return []*Format{
+		jsonFormat,
+		{
+			Name:      FormatXML,
+			MediaType: "application/xml",
+			Aliases:   []string{"text/xml"},
+			Encoder:   response.XMLEncoder{},
+			Envelope:  DataEnvelope,
+		},
+		{
+			Name:      FormatYAML,
+			MediaType: "application/yaml",
+			Aliases:   []string{"application/x-yaml", "text/yaml"},
+			Encoder:   response.YAMLEncoder{},
+			Envelope:  DataEnvelope,
+		},
+		{
+			Name:      FormatMsgpack,
+			MediaType: "application/msgpack",
+			Aliases:   []string{"application/x-msgpack"},
+			Encoder:   response.MsgpackEncoder{},
+			Envelope:  DataEnvelope,
+		},
+		{
+			Name:      FormatCSV,
+			MediaType: "text/csv",
+			Encoder:   response.CSVEncoder{},
+		},
+		{
+			Name:      FormatText,
+			MediaType: "text/plain",
+			Encoder:   response.TextEncoder{},
+		},
+	}
+}
+
+// RegisterFormat adds format or replaces the one with the same name.
+// The first registered format is used when the client does not ask for any.
+func (p *Prog) RegisterFormat(f Format) {
+	for i, existing := range p.formats {
+		if existing.Name == f.Name {
+			p.formats[i] = &f
+			return
+		}
+	}
+	p.formats = append(p.formats, &f)
+}
+
+// format returns format by its name
+func (p *Prog) format(name string) *Format {
+	for _, f := range p.formats {
+		if strings.EqualFold(f.Name, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+func (p *Prog) defaultFormat() *Format {
+	if len(p.formats) == 0 {
+		return jsonFormat
+	}
+	return p.formats[0]
+}
+
+// SetFormat overrides negotiated response format with the named one
+func (ctx *Ctx) SetFormat(name string) {
+	ctx.format = name
+}
+
+// negotiate selects response format from the override, format flag or Accept header in that order
+func (ctx *Ctx) negotiate() (*Format, error) {
+	name := ctx.format
+	if name == "" {
+		name = ctx.Flag(FormatFlag)
+	}
+	if name != "" {
+		f := ctx.Prog.format(name)
+		if f == nil {
+			return nil, ctx.NotAcceptable(fmt.Sprintf("unsupported format: %v", name))
+		}
+		return f, nil
+	}
+	accept := ctx.Request.Header().Get("Accept")
+	if accept == "" {
+		return ctx.Prog.defaultFormat(), nil
+	}
+	for _, mediaType := range parseAccept(accept) {
+		if mediaType == "*/*" {
+			return ctx.Prog.defaultFormat(), nil
+		}
+		for _, f := range ctx.Prog.formats {
+			if f.matches(mediaType) {
+				return f, nil
+			}
+			if strings.HasSuffix(mediaType, "/*") && strings.HasPrefix(f.MediaType, strings.TrimSuffix(mediaType, "*")) {
+				return f, nil
+			}
+		}
+	}
+	return nil, ctx.NotAcceptable(fmt.Sprintf("none of accepted media types is supported: %v", accept))
+}
+
+// parseAccept returns media types of Accept header ordered by quality, types with q=0 are dropped
+func parseAccept(accept string) []string {
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		r := mediaRange{
+			mediaType: strings.ToLower(strings.TrimSpace(params[0])),
+			q:         1,
+		}
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && kv[0] == "q" {
+				q, err := strconv.ParseFloat(kv[1], 64)
+				if err == nil {
+					r.q = q
+				}
+			}
+		}
+		if r.mediaType != "" && r.q > 0 {
+			ranges = append(ranges, r)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	mediaTypes := make([]string, len(ranges))
+	for i, r := range ranges {
+		mediaTypes[i] = r.mediaType
+	}
+	return mediaTypes
+}
+
+// Respond writes body in the format negotiated with the client, it returns ErrorNotAcceptable
+// when none of the registered formats is accepted
+func (ctx *Ctx) Respond(body interface{}) error {
+	f, err := ctx.negotiate()
+	if err != nil {
+		return err
+	}
+	return ctx.encode(f, body)
+}
+
+// encode writes body wrapped into the envelope of the format
+func (ctx *Ctx) encode(f *Format, body interface{}) error {
+	ctx.Response.SetHeader("Content-Type", f.MediaType)
+	ctx.Response.SetEncoder(f.Encoder)
+	if f.Envelope != nil {
+		body = f.Envelope(body)
+	}
+	return ctx.Response.Write(body)
+}
@@ -0,0 +1,84 @@
+package wrap
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/response"
+)
+
+type formatUser struct {
+	Name string `json:"name" xml:"name" yaml:"name"`
+	Age  int    `json:"age" xml:"age" yaml:"age"`
+}
+
+func TestRespond(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("users", func(c *Ctx) error {
+		return c.Respond([]formatUser{{Name: "ann", Age: 30}})
+	})
+	w := serveRequest(p, http.MethodGet, "/users", nil, nil)
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(`{"data":[{"name":"ann","age":30}]}`, w.Body.String())
+
+	w = serveRequest(p, http.MethodGet, "/users", nil, map[string]string{"Accept": "application/yaml"})
+	assert.Equal("application/yaml", w.Header().Get("Content-Type"))
+	assert.Equal("data:\n    - name: ann\n      age: 30\n", w.Body.String())
+
+	w = serveRequest(p, http.MethodGet, "/users", nil, map[string]string{"Accept": "text/csv"})
+	assert.Equal("name,age\nann,30\n", w.Body.String())
+
+	w = serveRequest(p, http.MethodGet, "/users", nil, map[string]string{"Accept": "text/html;q=0.9, application/xml;q=0.8, text/csv;q=0.1"})
+	assert.Equal("application/xml", w.Header().Get("Content-Type"))
+	assert.Contains(w.Body.String(), "<response><data><name>ann</name><age>30</age></data></response>")
+
+	w = serveRequest(p, http.MethodGet, "/users?format=csv", nil, map[string]string{"Accept": "application/json"})
+	assert.Equal("text/csv", w.Header().Get("Content-Type"))
+
+	w = serveRequest(p, http.MethodGet, "/users", nil, map[string]string{"Accept": "text/*"})
+	assert.Equal("text/csv", w.Header().Get("Content-Type"))
+}
+
+func TestNotAcceptable(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("users", func(c *Ctx) error {
+		return c.Respond("ann")
+	})
+	w := serveRequest(p, http.MethodGet, "/users", nil, map[string]string{"Accept": "image/png"})
+	assert.Equal(http.StatusNotAcceptable, w.Code)
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+
+	w = serveRequest(p, http.MethodGet, "/users?format=toml", nil, nil)
+	assert.Equal(http.StatusNotAcceptable, w.Code)
+	assert.JSONEq(`{"message":"unsupported format: toml"}`, w.Body.String())
+}
+
+func TestErrorFormat(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	w := serveRequest(p, http.MethodGet, "/missing", nil, map[string]string{"Accept": "application/yaml"})
+	assert.Equal(http.StatusNotFound, w.Code)
+	assert.Equal("application/yaml", w.Header().Get("Content-Type"))
+	assert.Contains(w.Body.String(), "message:")
+
+	p.RegisterFormat(Format{Name: FormatText, MediaType: "text/plain; charset=utf-8", Aliases: []string{"text/plain"}, Encoder: jsonFormat.Encoder})
+	assert.Equal("text/plain; charset=utf-8", p.format(FormatText).MediaType)
+}
+
+type csvColumn string
+
+func TestCSVMaps(t *testing.T) {
+	assert := assert.New(t)
+	var b bytes.Buffer
+	err := response.CSVEncoder{}.Encode(&b, []map[csvColumn]int{{"a": 1, "b": 2}, {"a": 3}})
+	assert.NoError(err)
+	assert.Equal("a,b\n1,2\n3,\n", b.String())
+
+	b.Reset()
+	err = response.CSVEncoder{}.Encode(&b, []map[int]string{{1: "a"}})
+	assert.EqualError(err, "csv: unsupported map key type int")
+}
@@ -0,0 +1,19 @@
+package wrap
+
+import (
+	"io"
+	"net/http/httptest"
+)
+
+// serveRequest serves request with method, target, body and headers by p, empty headers are not set
+func serveRequest(p *Prog, method, target string, body io.Reader, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, body)
+	for k, v := range header {
+		if v != "" {
+			r.Header.Set(k, v)
+		}
+	}
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,185 @@
+package wrap
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultDrainTimeout is time given to in-flight requests to finish on shutdown
+const DefaultDrainTimeout = 30 * time.Second
+
+const drainPollInterval = 10 * time.Millisecond
+
+// Hook is a function called when the app starts or shuts down
+type Hook func(context.Context) error
+
+// lifecycle holds the state used for starting and stopping the app
+type lifecycle struct {
+	mu         sync.Mutex
+	onStart    []Hook
+	onShutdown []Hook
+	servers    []*http.Server
+	startOnce  sync.Once
+	startErr   error
+	stopOnce   sync.Once
+	done       chan struct{}
+	active     int
+	base       context.Context
+	cancel     context.CancelFunc
+}
+
+func newLifecycle() *lifecycle {
+	base, cancel := context.WithCancel(context.Background())
+	return &lifecycle{
+		done:   make(chan struct{}),
+		base:   base,
+		cancel: cancel,
+	}
+}
+
+// OnStart registers hook which is called once before the first transport starts serving
+func (p *Prog) OnStart(h Hook) {
+	p.lifecycle.mu.Lock()
+	defer p.lifecycle.mu.Unlock()
+	p.lifecycle.onStart = append(p.lifecycle.onStart, h)
+}
+
+// OnShutdown registers hook which is called after in-flight requests are drained,
+// hooks are called in reverse order of registration
+func (p *Prog) OnShutdown(h Hook) {
+	p.lifecycle.mu.Lock()
+	defer p.lifecycle.mu.Unlock()
+	p.lifecycle.onShutdown = append(p.lifecycle.onShutdown, h)
+}
+
+// Run calls start hooks and blocks until ctx is done or SIGINT/SIGTERM is received,
+// then shuts the app down giving in-flight requests DrainTimeout to finish
+func (p *Prog) Run(ctx context.Context) error {
+	err := p.start(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case <-ctx.Done():
+	case <-p.lifecycle.done:
+	}
+	p.Logger.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), p.DrainTimeout)
+	defer cancel()
+	return p.Shutdown(shutdownCtx)
+}
+
+// Shutdown stops accepting new requests on every started transport, waits for
+// in-flight requests to finish and calls shutdown hooks.
+// If ctx expires before the requests are drained, contexts of in-flight requests are canceled,
+// hooks are still called and ctx error is returned.
+func (p *Prog) Shutdown(ctx context.Context) error {
+	var err error
+	p.lifecycle.stopOnce.Do(func() {
+		close(p.lifecycle.done)
+		p.lifecycle.mu.Lock()
+		servers := append([]*http.Server{p.Server}, p.lifecycle.servers...)
+		hooks := p.lifecycle.onShutdown
+		p.lifecycle.mu.Unlock()
+		for _, s := range servers {
+			e := s.Shutdown(ctx)
+			if e != nil && err == nil {
+				err = e
+			}
+		}
+		p.Hub.closeAll()
+		e := p.drain(ctx)
+		p.lifecycle.cancel()
+		if e != nil && err == nil {
+			err = e
+		}
+		// websocket connections send close frames once their replies are written
+		p.Hub.wait(ctx)
+		for i := len(hooks) - 1; i >= 0; i-- {
+			e := hooks[i](ctx)
+			if e != nil {
+				p.Logger.Error("shutdown hook failed", "error", e)
+				if err == nil {
+					err = e
+				}
+			}
+		}
+	})
+	return err
+}
+
+// start calls start hooks once
+func (p *Prog) start(ctx context.Context) error {
+	p.lifecycle.startOnce.Do(func() {
+		p.lifecycle.mu.Lock()
+		hooks := p.lifecycle.onStart
+		p.lifecycle.mu.Unlock()
+		for _, h := range hooks {
+			err := h(ctx)
+			if err != nil {
+				p.lifecycle.startErr = err
+				return
+			}
+		}
+	})
+	return p.lifecycle.startErr
+}
+
+// track registers server which is shut down together with the app
+func (p *Prog) track(s *http.Server) {
+	p.lifecycle.mu.Lock()
+	defer p.lifecycle.mu.Unlock()
+	p.lifecycle.servers = append(p.lifecycle.servers, s)
+}
+
+// stopping reports whether shutdown has started
+func (p *Prog) stopping() bool {
+	select {
+	case <-p.lifecycle.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Prog) begin() {
+	p.lifecycle.mu.Lock()
+	defer p.lifecycle.mu.Unlock()
+	p.lifecycle.active++
+}
+
+func (p *Prog) end() {
+	p.lifecycle.mu.Lock()
+	defer p.lifecycle.mu.Unlock()
+	p.lifecycle.active--
+}
+
+func (p *Prog) inflight() int {
+	p.lifecycle.mu.Lock()
+	defer p.lifecycle.mu.Unlock()
+	return p.lifecycle.active
+}
+
+// drain waits until there are no in-flight requests or ctx is done
+func (p *Prog) drain(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if p.inflight() == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			p.Logger.Error("shutdown timed out", "inflight", p.inflight())
+			return ctx.Err()
+		}
+	}
+}
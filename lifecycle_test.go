@@ -0,0 +1,100 @@
+package wrap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+)
+
+func TestRunHooks(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var calls []string
+	p.OnStart(func(context.Context) error {
+		calls = append(calls, "start")
+		return nil
+	})
+	p.OnShutdown(func(context.Context) error {
+		calls = append(calls, "close db")
+		return nil
+	})
+	p.OnShutdown(func(context.Context) error {
+		calls = append(calls, "close cache")
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NoError(p.Run(ctx))
+	assert.Equal([]string{"start", "close cache", "close db"}, calls)
+}
+
+func TestShutdownDrainsInflight(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	started := make(chan struct{})
+	finished := false
+	p.Get("slow", func(c *Ctx) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		finished = true
+		return c.JSON("done")
+	})
+	go serve(p, request.GET, "slow")
+	<-started
+	assert.NoError(p.Shutdown(context.Background()))
+	assert.True(finished)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	p.Get("stuck", func(c *Ctx) error {
+		close(started)
+		<-release
+		return nil
+	})
+	done := make(chan struct{})
+	go func() {
+		serve(p, request.GET, "stuck")
+		close(done)
+	}()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, p.Shutdown(ctx))
+	close(release)
+	<-done
+}
+
+func TestShutdownTimeoutCancelsHTTP(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	started := make(chan struct{})
+	p.Get("stuck", func(c *Ctx) error {
+		close(started)
+		<-c.Context().Done()
+		return c.Context().Err()
+	})
+	done := make(chan struct{})
+	w := httptest.NewRecorder()
+	go func() {
+		p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stuck", nil))
+		close(done)
+	}()
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, p.Shutdown(ctx))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("http request context was not canceled")
+	}
+}
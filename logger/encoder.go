@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encoder formats log entry
+type Encoder interface {
+	Encode(w io.Writer, e Entry) error
+}
+
+// JSONEncoder writes every entry as a single JSON object
+type JSONEncoder struct{}
+
+// Encode writes entry as JSON line
+func (JSONEncoder) Encode(w io.Writer, e Entry) error {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeJSON(&buf, e.Time.Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSON(&buf, e.Level.String())
+	buf.WriteString(`,"msg":`)
+	writeJSON(&buf, e.Message)
+	if e.Caller != "" {
+		buf.WriteString(`,"caller":`)
+		writeJSON(&buf, e.Caller)
+	}
+	for _, f := range e.Fields {
+		buf.WriteByte(',')
+		writeJSON(&buf, f.Key)
+		buf.WriteByte(':')
+		writeJSON(&buf, jsonValue(f.Value))
+	}
+	buf.WriteString("}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case error:
+		return val.Error()
+	case time.Duration:
+		return val.String()
+	case fmt.Stringer:
+		return val.String()
+	}
+	return v
+}
+
+// LogfmtEncoder writes every entry as key=value pairs
+type LogfmtEncoder struct{}
+
+// Encode writes entry as logfmt line
+func (LogfmtEncoder) Encode(w io.Writer, e Entry) error {
+	var buf bytes.Buffer
+	buf.WriteString("time=")
+	buf.WriteString(e.Time.Format(time.RFC3339Nano))
+	buf.WriteString(" level=")
+	buf.WriteString(e.Level.String())
+	buf.WriteString(" msg=")
+	buf.WriteString(logfmtValue(e.Message))
+	if e.Caller != "" {
+		buf.WriteString(" caller=")
+		buf.WriteString(logfmtValue(e.Caller))
+	}
+	writeFields(&buf, e.Fields)
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeFields(buf *bytes.Buffer, fields []Field) {
+	for _, f := range fields {
+		buf.WriteByte(' ')
+		buf.WriteString(f.Key)
+		buf.WriteByte('=')
+		buf.WriteString(logfmtValue(fmt.Sprint(f.Value)))
+	}
+}
+
+func logfmtValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// TextEncoder writes human readable lines, Color adds ANSI colors to the level
+type TextEncoder struct {
+	Color bool
+}
+
+// Encode writes entry as text line
+func (t TextEncoder) Encode(w io.Writer, e Entry) error {
+	var buf bytes.Buffer
+	buf.WriteString(e.Time.Format("2006/01/02 15:04:05"))
+	buf.WriteByte(' ')
+	level := fmt.Sprintf("%-5s", strings.ToUpper(e.Level.String()))
+	if t.Color {
+		level = levelColors[e.Level] + level + "\033[00m"
+	}
+	buf.WriteString(level)
+	buf.WriteByte(' ')
+	buf.WriteString(e.Message)
+	writeFields(&buf, e.Fields)
+	if e.Caller != "" {
+		buf.WriteString(" caller=")
+		buf.WriteString(e.Caller)
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+var levelColors = map[Level]string{
+	DebugLevel: "\033[90m",
+	InfoLevel:  "\033[36m",
+	WarnLevel:  "\033[33m",
+	ErrorLevel: "\033[31m",
+}
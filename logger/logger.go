@@ -2,111 +2,336 @@ package logger
 
 import (
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
+	"time"
 )
 
-var (
-	info  = log.New(os.Stdout, "\033[36mINFO\033[00m  ", log.LstdFlags)
-	warn  = log.New(os.Stdout, "\033[33mWARN\033[00m  ", log.LstdFlags)
-	error = log.New(os.Stderr, "\033[31mERROR\033[00m ", log.LstdFlags)
-)
+// Level describes severity of the log entry
+type Level int
 
+// list of log levels
 const (
-	infoCall int = 1 + iota
-	warnCall
-	errorCall
+	DebugLevel Level = 1 + iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+// String returns lowercase name of the level
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	}
+	return ""
+}
+
+// ParseLevel returns level by its name
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	}
+	return 0, fmt.Errorf("invalid log level: %v", s)
+}
+
+// Field is key/value pair attached to the log entry
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Entry is a single log record passed to sinks
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Caller  string
+	Fields  []Field
+}
+
+// Sink receives log entries
+type Sink interface {
+	Write(e Entry) error
+}
+
+// NewSink returns sink which encodes entries with enc and writes them to w
+func NewSink(w io.Writer, enc Encoder) Sink {
+	return &writerSink{
+		w:   w,
+		enc: enc,
+	}
+}
+
+type writerSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc Encoder
+}
+
+func (s *writerSink) Write(e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(s.w, e)
+}
+
+// NewSplitSink returns sink which writes errors to errOut and everything else to out
+func NewSplitSink(out, errOut io.Writer, enc Encoder) Sink {
+	return &splitSink{
+		out: NewSink(out, enc),
+		err: NewSink(errOut, enc),
+	}
+}
+
+type splitSink struct {
+	out Sink
+	err Sink
+}
+
+func (s *splitSink) Write(e Entry) error {
+	if e.Level >= ErrorLevel {
+		return s.err.Write(e)
+	}
+	return s.out.Write(e)
+}
+
+// Logger writes leveled entries with key/value fields to its sinks
+type Logger struct {
+	level  *Level
+	mu     *sync.RWMutex
+	sinks  []Sink
+	fields []Field
+}
+
+// New creates logger which writes entries of level and above to sinks
+func New(level Level, sinks ...Sink) *Logger {
+	return &Logger{
+		level: &level,
+		mu:    new(sync.RWMutex),
+		sinks: sinks,
+	}
+}
+
+// With returns child logger which attaches key/value pairs to every entry,
+// the child shares level and sinks with the parent
+func (l *Logger) With(kv ...interface{}) *Logger {
+	fields := make([]Field, 0, len(l.fields)+len(kv)/2)
+	fields = append(fields, l.fields...)
+	return &Logger{
+		level:  l.level,
+		mu:     l.mu,
+		sinks:  l.sinks,
+		fields: append(fields, toFields(kv)...),
+	}
+}
+
+// WithSinks returns child logger which writes to sinks instead of the parent sinks,
+// the child shares level and fields with the parent
+func (l *Logger) WithSinks(sinks ...Sink) *Logger {
+	return &Logger{
+		level:  l.level,
+		mu:     l.mu,
+		sinks:  sinks,
+		fields: l.fields,
+	}
+}
+
+// SetLevel sets minimal level of written entries
+func (l *Logger) SetLevel(level Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.level = level
+}
+
+// Enabled reports whether entries of the level are written
+func (l *Logger) Enabled(level Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return level >= *l.level
+}
+
+// Debug writes debug entry with key/value pairs
+func (l *Logger) Debug(msg string, kv ...interface{}) {
+	l.log(DebugLevel, caller(1), msg, kv)
+}
+
+// Info writes info entry with key/value pairs
+func (l *Logger) Info(msg string, kv ...interface{}) {
+	l.log(InfoLevel, caller(1), msg, kv)
+}
+
+// Warn writes warn entry with key/value pairs
+func (l *Logger) Warn(msg string, kv ...interface{}) {
+	l.log(WarnLevel, caller(1), msg, kv)
+}
+
+// Error writes error entry with key/value pairs
+func (l *Logger) Error(msg string, kv ...interface{}) {
+	l.log(ErrorLevel, caller(1), msg, kv)
+}
+
+func (l *Logger) log(level Level, caller string, msg string, kv []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	e := Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+		Caller:  caller,
+		Fields:  append(append([]Field{}, l.fields...), toFields(kv)...),
+	}
+	for _, s := range l.sinks {
+		err := s.Write(e)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "logger: %v\n", err)
+		}
+	}
+}
+
+// toFields converts alternating keys and values into fields, a key without value gets nil
+func toFields(kv []interface{}) []Field {
+	fields := make([]Field, 0, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key := fmt.Sprint(kv[i])
+		var val interface{}
+		if i+1 < len(kv) {
+			val = kv[i+1]
+		}
+		fields = append(fields, Field{Key: key, Value: val})
+	}
+	return fields
+}
+
+// caller returns file:line of the caller, skip 0 is the function calling caller
+func caller(skip int) string {
+	_, f, l, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%v:%v", filepath.Join(filepath.Base(filepath.Dir(f)), filepath.Base(f)), l)
+}
+
+var (
+	stdMu sync.RWMutex
+	std   = New(InfoLevel, NewSplitSink(os.Stdout, os.Stderr, TextEncoder{Color: isTerminal(os.Stdout)}))
 )
 
-// Info calls Output to print to the info logger.
+// Default returns logger used by package level functions
+func Default() *Logger {
+	stdMu.RLock()
+	defer stdMu.RUnlock()
+	return std
+}
+
+// SetDefault replaces logger used by package level functions
+func SetDefault(l *Logger) {
+	stdMu.Lock()
+	defer stdMu.Unlock()
+	std = l
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Debug writes to the default logger.
+// Arguments are handled in the manner of fmt.Print.
+func Debug(v ...interface{}) {
+	Default().log(DebugLevel, caller(1), fmt.Sprint(v...), nil)
+}
+
+// Debugf writes to the default logger.
+// Arguments are handled in the manner of fmt.Printf.
+func Debugf(format string, v ...interface{}) {
+	Default().log(DebugLevel, caller(1), fmt.Sprintf(format, v...), nil)
+}
+
+// Info writes to the default logger.
 // Arguments are handled in the manner of fmt.Print.
 func Info(v ...interface{}) {
-	v = append([]interface{}{Caller(infoCall)}, v...)
-	info.Print(v...)
+	Default().log(InfoLevel, caller(1), fmt.Sprint(v...), nil)
 }
 
-// Infof calls Output to print to the info logger.
+// Infof writes to the default logger.
 // Arguments are handled in the manner of fmt.Printf.
 func Infof(format string, v ...interface{}) {
-	v = append([]interface{}{Caller(infoCall)}, v...)
-	format = addCallerFormat(format)
-	info.Printf(format, v...)
+	Default().log(InfoLevel, caller(1), fmt.Sprintf(format, v...), nil)
 }
 
-// Warn calls Output to print to the warn logger.
+// Warn writes to the default logger.
 // Arguments are handled in the manner of fmt.Print.
 func Warn(v ...interface{}) {
-	v = append([]interface{}{Caller(warnCall)}, v...)
-	warn.Print(v...)
+	Default().log(WarnLevel, caller(1), fmt.Sprint(v...), nil)
 }
 
-// Warnf calls Output to print to the warn logger.
+// Warnf writes to the default logger.
 // Arguments are handled in the manner of fmt.Printf.
 func Warnf(format string, v ...interface{}) {
-	v = append([]interface{}{Caller(warnCall)}, v...)
-	format = addCallerFormat(format)
-	warn.Printf(format, v...)
+	Default().log(WarnLevel, caller(1), fmt.Sprintf(format, v...), nil)
 }
 
-// Error calls Output to print to the error logger.
+// Error writes to the default logger.
 // Arguments are handled in the manner of fmt.Print.
 func Error(v ...interface{}) {
-	v = append([]interface{}{Caller(errorCall)}, v...)
-	error.Print(v...)
+	Default().log(ErrorLevel, caller(1), fmt.Sprint(v...), nil)
 }
 
-// Errorf calls Output to print to the error logger.
+// Errorf writes to the default logger.
 // Arguments are handled in the manner of fmt.Printf.
 func Errorf(format string, v ...interface{}) {
-	v = append([]interface{}{Caller(errorCall)}, v...)
-	format = addCallerFormat(format)
-	error.Printf(format, v...)
+	Default().log(ErrorLevel, caller(1), fmt.Sprintf(format, v...), nil)
 }
 
-// ErrorWithCaller calls Output to print to the error logger.
+// ErrorWithCaller writes to the default logger with the given caller.
 // Arguments are handled in the manner of fmt.Print.
 func ErrorWithCaller(caller string, v ...interface{}) {
-	v = append([]interface{}{caller}, v...)
-	error.Print(v...)
+	Default().log(ErrorLevel, caller, fmt.Sprint(v...), nil)
 }
 
-// ErrorfWithCaller calls Output to print to the error logger.
+// ErrorfWithCaller writes to the default logger with the given caller.
 // Arguments are handled in the manner of fmt.Printf.
 func ErrorfWithCaller(caller string, format string, v ...interface{}) {
-	v = append([]interface{}{caller}, v...)
-	format = addCallerFormat(format)
-	error.Printf(format, v...)
+	Default().log(ErrorLevel, caller, fmt.Sprintf(format, v...), nil)
 }
 
 // Panic is equivalent to Error() followed by a call to panic().
 func Panic(v ...interface{}) {
-	v = append([]interface{}{Caller(errorCall)}, v...)
-	error.Print(v...)
-	panic(fmt.Sprint(v...))
+	msg := fmt.Sprint(v...)
+	Default().log(ErrorLevel, caller(1), msg, nil)
+	panic(msg)
 }
 
 // Panicf is equivalent to Errorf() followed by a call to panic().
 func Panicf(format string, v ...interface{}) {
-	v = append([]interface{}{Caller(errorCall)}, v...)
-	format = addCallerFormat(format)
-	error.Printf(format, v...)
-	panic(fmt.Sprintf(format, v...))
+	msg := fmt.Sprintf(format, v...)
+	Default().log(ErrorLevel, caller(1), msg, nil)
+	panic(msg)
 }
 
+// Caller returns file:line of the function which called the caller of Caller,
+// c is ignored and kept for compatibility
 func Caller(c int) string {
-	_, f, l, _ := runtime.Caller(2)
-	switch c {
-	case infoCall:
-		return fmt.Sprintf("%v:%v: \n  \033[36m>>\033[00m  ", f, l)
-	case warnCall:
-		return fmt.Sprintf("%v:%v: \n  \033[33m>>\033[00m  ", f, l)
-	case errorCall:
-		return fmt.Sprintf("%v:%v: \n  \033[31m>>\033[00m  ", f, l)
-	}
-	return fmt.Sprintf("%v:%v: \n  \033[36m>>\033[00m  ", f, l)
-}
-
-func addCallerFormat(format string) string {
-	return "%v" + format
+	return caller(2)
 }
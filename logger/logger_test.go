@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelFiltering(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	l := New(WarnLevel, NewSink(&buf, LogfmtEncoder{}))
+	l.Info("skipped")
+	l.Warn("written")
+	assert.NotContains(buf.String(), "skipped")
+	assert.Contains(buf.String(), "level=warn msg=written")
+	l.SetLevel(DebugLevel)
+	l.Debug("debug")
+	assert.Contains(buf.String(), "level=debug msg=debug")
+}
+
+func TestWithSinks(t *testing.T) {
+	assert := assert.New(t)
+	var out, errOut bytes.Buffer
+	l := New(WarnLevel, NewSink(&out, LogfmtEncoder{})).With("app", "a")
+	c := l.WithSinks(NewSink(&errOut, LogfmtEncoder{}))
+	c.Info("skipped")
+	c.Warn("moved")
+	assert.Empty(out.String())
+	assert.Contains(errOut.String(), "level=warn msg=moved")
+	assert.Contains(errOut.String(), "app=a")
+}
+
+func TestJSONEncoder(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	l := New(InfoLevel, NewSink(&buf, JSONEncoder{})).With("request_id", "abc")
+	l.Error("failed", "error", errors.New("boom"), "status", 500)
+	line := buf.String()
+	assert.True(strings.HasSuffix(line, "\n"))
+	assert.Contains(line, `"level":"error","msg":"failed","caller":"logger/logger_test.go:`)
+	assert.Contains(line, `"request_id":"abc","error":"boom","status":500}`)
+}
+
+func TestLogfmtAndTextEncoders(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	l := New(InfoLevel, NewSink(&buf, LogfmtEncoder{}))
+	l.Info("user created", "name", "John Doe", "empty", "")
+	assert.Contains(buf.String(), `msg="user created"`)
+	assert.Contains(buf.String(), `name="John Doe" empty=""`)
+	buf.Reset()
+	l = New(InfoLevel, NewSink(&buf, TextEncoder{}))
+	l.Info("plain", "k", "v")
+	assert.NotContains(buf.String(), "\033")
+	assert.Contains(buf.String(), "INFO  plain k=v caller=logger/logger_test.go:")
+}
+
+func TestFacade(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	old := Default()
+	defer SetDefault(old)
+	SetDefault(New(InfoLevel, NewSink(&buf, LogfmtEncoder{})))
+	Infof("hello %v", "world")
+	Errorf("code %d", 42)
+	assert.Contains(buf.String(), `level=info msg="hello world" caller=logger/logger_test.go:`)
+	assert.Contains(buf.String(), `level=error msg="code 42"`)
+}
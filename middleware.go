@@ -0,0 +1,208 @@
+package wrap
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/torniker/wrap/request"
+)
+
+// Middleware wraps HandlerFunc with cross-cutting logic.
+// Middleware is executed in the order it was registered: global middleware
+// first, then group middleware and route middleware last. The code placed
+// after the call to next runs once the inner handlers returned, at which point
+// errors returned by the handler are already written to the response.
+// next is skipped when the response was already committed.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Use appends global middleware which is executed for every request of every transport
+func (p *Prog) Use(m ...Middleware) {
+	p.middleware = append(p.middleware, m...)
+}
+
+// handle runs ctx through global middleware and DefaultHandler, panics are converted to ErrorInternalServerError
+func (p *Prog) handle(ctx *Ctx) (err error) {
+	p.begin()
+	defer p.end()
+	defer func() {
+		if v := recover(); v != nil {
+			err = p.recovered(ctx, v)
+			if ctx.Response.Commited() {
+				err = nil
+			}
+		}
+	}()
+	return chain(p.DefaultHandler, p.middleware)(ctx)
+}
+
+// finisher is implemented by responses which have to reply even if the handler did not write anything
+type finisher interface {
+	Finish() error
+}
+
+// serve handles ctx of a transport, writes returned error into the response and records it in access log
+func (p *Prog) serve(ctx *Ctx) {
+	start := time.Now()
+	limitBody(ctx, p.MaxBodySize)
+	err := p.handle(ctx)
+	if err != nil {
+		ctx.Error(err)
+	}
+	if f, ok := ctx.Response.(finisher); ok {
+		f.Finish()
+	}
+	p.AccessLog.record(ctx, start)
+	if c, ok := ctx.Request.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// chain wraps h with middleware, errors returned by h are written to the response
+// so that middleware can inspect the final status
+func chain(h HandlerFunc, m []Middleware) HandlerFunc {
+	if len(m) == 0 {
+		return h
+	}
+	h = respond(h)
+	for i := len(m) - 1; i >= 0; i-- {
+		h = m[i](skipCommitted(h))
+	}
+	return h
+}
+
+func respond(h HandlerFunc) HandlerFunc {
+	return func(ctx *Ctx) error {
+		err := h(ctx)
+		if err != nil {
+			ctx.Error(err)
+		}
+		return nil
+	}
+}
+
+func skipCommitted(h HandlerFunc) HandlerFunc {
+	return func(ctx *Ctx) error {
+		if ctx.Response.Commited() {
+			return nil
+		}
+		return h(ctx)
+	}
+}
+
+// Timeout sets deadline on the request context, handlers which do not finish
+// in time respond with ErrorServiceUnavailable
+func Timeout(d time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Ctx) error {
+			parent := ctx.Context()
+			c, cancel := context.WithTimeout(parent, d)
+			defer cancel()
+			ctx.SetContext(c)
+			defer ctx.SetContext(parent)
+			err := next(ctx)
+			if err == nil && c.Err() == context.DeadlineExceeded && !ctx.Response.Commited() {
+				return ctx.ServiceUnavailable("request timeout")
+			}
+			return err
+		}
+	}
+}
+
+// Group registers routes sharing a path prefix and middleware
+type Group struct {
+	prefix     string
+	router     *Router
+	parent     *Group
+	middleware []Middleware
+}
+
+// Group creates route group with the prefix and middleware
+func (p *Prog) Group(prefix string, m ...Middleware) *Group {
+	return &Group{
+		prefix:     strings.Trim(prefix, "/"),
+		router:     p.Router,
+		middleware: m,
+	}
+}
+
+// Group creates nested route group which inherits prefix and middleware of the parent
+func (g *Group) Group(prefix string, m ...Middleware) *Group {
+	return &Group{
+		prefix:     g.pattern(prefix),
+		router:     g.router,
+		parent:     g,
+		middleware: m,
+	}
+}
+
+// Use appends middleware to the group, it applies to routes registered before and after the call
+func (g *Group) Use(m ...Middleware) {
+	g.middleware = append(g.middleware, m...)
+}
+
+// Route registers handler for the pattern which is called for any action
+func (g *Group) Route(pattern string, h HandlerFunc, m ...Middleware) {
+	g.router.Route(g.pattern(pattern), g.wrap(h, m))
+}
+
+// Post registers handler for the pattern and POST action
+func (g *Group) Post(pattern string, h HandlerFunc, m ...Middleware) {
+	g.router.Handle(request.POST, g.pattern(pattern), g.wrap(h, m))
+}
+
+// Get registers handler for the pattern and GET action
+func (g *Group) Get(pattern string, h HandlerFunc, m ...Middleware) {
+	g.router.Handle(request.GET, g.pattern(pattern), g.wrap(h, m))
+}
+
+// Put registers handler for the pattern and PUT action
+func (g *Group) Put(pattern string, h HandlerFunc, m ...Middleware) {
+	g.router.Handle(request.PUT, g.pattern(pattern), g.wrap(h, m))
+}
+
+// Patch registers handler for the pattern and PATCH action
+func (g *Group) Patch(pattern string, h HandlerFunc, m ...Middleware) {
+	g.router.Handle(request.PATCH, g.pattern(pattern), g.wrap(h, m))
+}
+
+// Head registers handler for the pattern and HEAD action
+func (g *Group) Head(pattern string, h HandlerFunc, m ...Middleware) {
+	g.router.Handle(request.HEAD, g.pattern(pattern), g.wrap(h, m))
+}
+
+// Options registers handler for the pattern and OPTIONS action
+func (g *Group) Options(pattern string, h HandlerFunc, m ...Middleware) {
+	g.router.Handle(request.OPTIONS, g.pattern(pattern), g.wrap(h, m))
+}
+
+// Delete registers handler for the pattern and DELETE action
+func (g *Group) Delete(pattern string, h HandlerFunc, m ...Middleware) {
+	g.router.Handle(request.DELETE, g.pattern(pattern), g.wrap(h, m))
+}
+
+func (g *Group) pattern(pattern string) string {
+	pattern = strings.Trim(pattern, "/")
+	if g.prefix == "" {
+		return pattern
+	}
+	if pattern == "" {
+		return g.prefix
+	}
+	return g.prefix + "/" + pattern
+}
+
+// wrap builds the chain on every call so middleware added later with Use is applied as well
+func (g *Group) wrap(h HandlerFunc, m []Middleware) HandlerFunc {
+	return func(ctx *Ctx) error {
+		return chain(h, append(g.middlewares(), m...))(ctx)
+	}
+}
+
+func (g *Group) middlewares() []Middleware {
+	if g.parent == nil {
+		return append([]Middleware{}, g.middleware...)
+	}
+	return append(g.parent.middlewares(), g.middleware...)
+}
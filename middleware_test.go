@@ -0,0 +1,96 @@
+package wrap
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+)
+
+func trace(calls *[]string, name string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Ctx) error {
+			*calls = append(*calls, name+" before")
+			err := next(c)
+			*calls = append(*calls, name+" after")
+			return err
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var calls []string
+	p.Use(trace(&calls, "global"))
+	g := p.Group("api", trace(&calls, "group"))
+	g.Get("users", func(c *Ctx) error {
+		calls = append(calls, "handler")
+		return c.JSON("ok")
+	}, trace(&calls, "route"))
+	serve(p, request.GET, "/api/users")
+	assert.Equal([]string{
+		"global before",
+		"group before",
+		"route before",
+		"handler",
+		"route after",
+		"group after",
+		"global after",
+	}, calls)
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	called := false
+	p.Use(func(next HandlerFunc) HandlerFunc {
+		return func(c *Ctx) error {
+			c.Response.SetStatus(http.StatusUnauthorized)
+			c.Response.Write(ErrorUnauthorized{Message: "unauthorized"})
+			return next(c)
+		}
+	})
+	p.Get("users", func(c *Ctx) error {
+		called = true
+		return c.JSON("ok")
+	})
+	ctx := serve(p, request.GET, "/users")
+	assert.False(called)
+	assert.Equal(http.StatusUnauthorized, ctx.Response.Status())
+}
+
+func TestMiddlewareAfterSeesStatus(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var status int
+	p.Use(func(next HandlerFunc) HandlerFunc {
+		return func(c *Ctx) error {
+			err := next(c)
+			status = c.Response.Status()
+			return err
+		}
+	})
+	p.Get("users", func(c *Ctx) error {
+		return c.BadRequest("invalid")
+	})
+	serve(p, request.GET, "/users")
+	assert.Equal(http.StatusBadRequest, status)
+	serve(p, request.GET, "/missing")
+	assert.Equal(http.StatusNotFound, status)
+}
+
+func TestGroupUseAppliesToExistingRoutes(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var calls []string
+	g := p.Group("/admin/")
+	g.Group("users").Get(":id", func(c *Ctx) error {
+		return c.JSON(c.Param("id"))
+	})
+	g.Use(trace(&calls, "admin"))
+	ctx := serve(p, request.GET, "/admin/users/3")
+	assert.Equal(ResJSON{Data: "3"}, ctx.Response.Output())
+	assert.Equal([]string{"admin before", "admin after"}, calls)
+}
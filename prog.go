@@ -2,17 +2,21 @@ package wrap
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
-	"net/url"
 	"os"
-	"strings"
+	"sync"
 	"time"
 
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
 	"github.com/torniker/wrap/logger"
 	"github.com/torniker/wrap/request"
 	"github.com/torniker/wrap/response"
+	"golang.org/x/term"
 )
 
 // Progliication environments
@@ -22,25 +26,61 @@ const (
 	Development string = "Development"
 )
 
+// RequestIDHeader is http header which carries request id
+const RequestIDHeader = "X-Request-Id"
+
+// Timeouts of p.Server used when they are not configured
+const (
+	DefaultReadTimeout  = 5 * time.Second
+	DefaultWriteTimeout = 10 * time.Second
+)
+
 // Prog contains application data
 type Prog struct {
 	Env            string
 	Server         *http.Server
 	Store          map[string]interface{}
+	Router         *Router
+	Hub            *Hub
 	DefaultHandler HandlerFunc
+	DrainTimeout   time.Duration
+	MaxBodySize    int64
+	MaxMemory      int64
+	Heartbeat      time.Duration
+	HistoryFile    string
+	Logger         *logger.Logger
+	AccessLog      *AccessLog
+	middleware     []Middleware
+	panicHooks     []PanicHook
+	validators     map[string]ValidatorFunc
+	rules          *sync.Map
+	formats        []*Format
+	lifecycle      *lifecycle
 }
 
 var p Prog
 
-// New creates Prog instance and sets default handler function
+// New creates Prog instance and sets default handler function which dispatches requests through the router
 func New() *Prog {
 	p = Prog{
 		Env:    Development,
 		Server: new(http.Server),
 		Store:  make(map[string]interface{}),
+		Router: NewRouter(),
+		Hub:    NewHub(),
 		DefaultHandler: func(c *Ctx) error {
-			return c.NotFound()
+			return c.Prog.Router.Serve(c)
 		},
+		DrainTimeout: DefaultDrainTimeout,
+		MaxBodySize:  DefaultMaxBodySize,
+		MaxMemory:    request.DefaultMaxMemory,
+		Heartbeat:    DefaultHeartbeat,
+		Logger:       logger.Default(),
+		AccessLog:    NewAccessLog(nil, nil),
+		lifecycle:    newLifecycle(),
+		validators:   make(map[string]ValidatorFunc),
+		rules:        new(sync.Map),
+		formats:      defaultFormats(),
 	}
 	return &p
 }
@@ -50,99 +90,127 @@ func Instance() *Prog {
 	return &p
 }
 
-// StartHTTP starts web server
+// StartHTTP starts web server, it returns nil after the app is shut down
 func (p *Prog) StartHTTP(address string) error {
+	err := p.start(context.Background())
+	if err != nil {
+		return err
+	}
 	p.Server.Addr = address
+	p.prepareServer()
+	return ignoreClosed(p.Server.ListenAndServe())
+}
+
+// Serve serves the app on the listener, e.g. unix socket or in-memory listener in tests,
+// it returns nil after the app is shut down
+func (p *Prog) Serve(l net.Listener) error {
+	err := p.start(context.Background())
+	if err != nil {
+		return err
+	}
+	p.prepareServer()
+	return ignoreClosed(p.Server.Serve(l))
+}
+
+// prepareServer sets the app as handler of the server and default timeouts unless they are configured
+func (p *Prog) prepareServer() {
 	p.Server.Handler = p
-	p.Server.ReadTimeout = 5 * time.Second
-	p.Server.WriteTimeout = 10 * time.Second
-	return p.Server.ListenAndServe()
+	if p.Server.ReadTimeout == 0 {
+		p.Server.ReadTimeout = DefaultReadTimeout
+	}
+	if p.Server.WriteTimeout == 0 {
+		p.Server.WriteTimeout = DefaultWriteTimeout
+	}
 }
 
-// StartTLS starts web server with https support
+// StartTLS starts web server with https support, it returns nil after the app is shut down
 func (p *Prog) StartTLS(address, securedAddr string) error {
+	err := p.start(context.Background())
+	if err != nil {
+		return err
+	}
 	p.Server.Addr = securedAddr
-	p.Server.Handler = p
-	p.Server.ReadTimeout = 5 * time.Second
-	p.Server.WriteTimeout = 10 * time.Second
-	go http.ListenAndServe(address, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "https://localhost"+securedAddr+r.RequestURI, http.StatusMovedPermanently)
-	}))
-	return p.Server.ListenAndServeTLS("certs/server.crt", "certs/server.key")
+	p.prepareServer()
+	redirect := &http.Server{
+		Addr: address,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "https://localhost"+securedAddr+r.RequestURI, http.StatusMovedPermanently)
+		}),
+	}
+	p.track(redirect)
+	go func() {
+		err := ignoreClosed(redirect.ListenAndServe())
+		if err != nil {
+			p.Logger.Error("redirect server failed", "error", err)
+		}
+	}()
+	return ignoreClosed(p.Server.ListenAndServeTLS("certs/server.crt", "certs/server.key"))
 }
 
-// NewCtx returns pointer to Ctx
+func ignoreClosed(err error) error {
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// NewCtx returns pointer to Ctx, its context is canceled when the app shuts down
 func (p *Prog) NewCtx(req request.Request, resp response.Response) *Ctx {
 	return &Ctx{
 		Prog:     p,
 		Request:  req,
 		Response: resp,
+		id:       uuid.Must(uuid.NewV4()).String(),
+		context:  p.lifecycle.base,
 	}
 }
 
 func (p *Prog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		p.ServeWS(w, r)
+		return
+	}
 	ctx := p.NewCtx(request.NewHTTP(r), response.NewHTTP(w))
-	logger.Infof("---> method: %v, path: %v, query: %v", ctx.Request.Action().String(), ctx.Request.Path(), ctx.Request.Flags())
-	err := p.DefaultHandler(ctx)
-	if err != nil {
-		ctx.Error(err)
+	// the request context is also canceled when the app shuts down and draining times out
+	var cancel context.CancelFunc
+	ctx.context, cancel = context.WithCancel(r.Context())
+	defer cancel()
+	stop := context.AfterFunc(p.lifecycle.base, cancel)
+	defer stop()
+	if id := r.Header.Get(RequestIDHeader); id != "" {
+		ctx.id = id
 	}
+	ctx.Response.SetHeader(RequestIDHeader, ctx.id)
+	p.serve(ctx)
 }
 
 // StartCLI starts cli server
 func (p *Prog) StartCLI() error {
-	buf := bufio.NewReadWriter(bufio.NewReader(os.Stdin), bufio.NewWriter(os.Stdout))
+	err := p.start(context.Background())
+	if err != nil {
+		return err
+	}
 	fmt.Println("--------------------------------------------------------")
 	fmt.Println("Please provide command in following format:")
-	fmt.Println("--> post command/path?flag1=foo&flag2=bar {\"input\":\"data\"}")
+	fmt.Println("--> post command/path?flag1=foo --flag2=bar -H \"Key: value\" {\"input\":\"data\"}")
 	fmt.Println("--------------------------------------------------------")
-	return p.ListenCLI(buf)
-}
-
-// ListenCLI listens to buffer input
-func (p *Prog) ListenCLI(buf *bufio.ReadWriter) error {
-	for {
-		fmt.Print("--> ")
-		commandBytes, err := buf.ReadBytes('\n')
-		if err != nil {
-			return err
-		}
-		command := string(commandBytes)
-		if strings.ToLower(strings.TrimSpace(command)) == "exit" {
-			return nil
-		}
-		parts := strings.SplitN(command, " ", 3)
-		var addr string
-		var action request.Action
-		var input io.Reader
-		if len(parts) == 2 {
-			action = request.GET
-			addr = "http://app.cli/" + strings.TrimSpace(parts[0])
-			input = strings.NewReader(parts[1])
-		} else if len(parts) == 3 {
-			action = request.NewActionFromString(parts[0])
-			if !action.IsValid() {
-				fmt.Println("invalid action")
-				continue
-			}
-			addr = "http://app.cli/" + strings.TrimSpace(parts[1])
-			input = strings.NewReader(parts[1])
-		} else {
-			fmt.Println("could not understand query")
-			continue
-		}
-		u, err := url.Parse(addr)
-		if err != nil {
-			fmt.Println(err)
-			continue
-		}
-		ctx := p.NewCtx(request.NewCLI(action, u, input), response.NewCLI())
-		logger.Infof("---> action: %v, path: %v, query: %v", ctx.Request.Action().String(), ctx.Request.Path(), ctx.Request.Flags())
-		err = p.DefaultHandler(ctx)
-		if err != nil {
-			ctx.Error(err)
-		}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return p.ListenCLI(bufio.NewReadWriter(bufio.NewReader(os.Stdin), bufio.NewWriter(os.Stdout)))
+	}
+	fmt.Println("Type help to list routes, tab completes actions and paths")
+	if p.HistoryFile == "" {
+		p.HistoryFile = defaultHistoryFile()
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
 	}
+	defer term.Restore(fd, state)
+	return p.REPL(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout})
 }
 
 // IsDevelopment is true if the env is development
@@ -159,83 +227,3 @@ func (p *Prog) IsTesting() bool {
 func (p *Prog) IsProduction() bool {
 	return p.Env == Production
 }
-
-// Call helps to create new request
-func (p *Prog) Call() *Requester {
-	return &Requester{
-		prog: p,
-		req:  &request.Req{},
-	}
-}
-
-// Requester wraps sub requests
-type Requester struct {
-	prog *Prog
-	req  *request.Req
-	err  error
-}
-
-// Bind tries to call the request and bind the data from the response
-func (r *Requester) Bind(v interface{}) error {
-	if r.err != nil {
-		return r.err
-	}
-	subCtx := r.prog.NewCtx(r.req, response.NewResponse())
-	err := r.prog.DefaultHandler(subCtx)
-	if err != nil {
-		return err
-	}
-	v = subCtx.Response.Output()
-	return nil
-}
-
-// Path sets command name for the request
-func (r *Requester) path(command string) *Requester {
-	u, err := url.Parse(command)
-	if err != nil {
-		r.err = err
-		return r
-	}
-	r.req.SetPath(u)
-	return r
-}
-
-// Flags sets flags for the request
-func (r *Requester) Flags(flags map[string][]string) *Requester {
-	r.req.SetFlags(flags)
-	return r
-}
-
-// Input sets input data for request
-func (r *Requester) Input(v interface{}) *Requester {
-	r.req.SetData(v)
-	return r
-}
-
-// Create command
-func (r *Requester) Create(command string) *Requester {
-	r.req.SetAction(request.POST)
-	r.path(command)
-	return r
-}
-
-// Read command
-func (r *Requester) Read(command string) *Requester {
-	r.req.SetAction(request.GET)
-	r.path(command)
-	return r
-}
-
-// Update command
-func (r *Requester) Update(command string) *Requester {
-	r.req.SetAction(request.PUT)
-	r.path(command)
-	return r
-}
-
-// Delete command
-func (r *Requester) Delete(command string) *Requester {
-	r.req.SetAction(request.DELETE)
-	r.path(command)
-	return r
-}
@@ -0,0 +1,32 @@
+package wrap
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicHook is called with the recovered value and the stack trace when a handler panics
+type PanicHook func(ctx *Ctx, v interface{}, stack []byte)
+
+// OnPanic registers hook which is called when a handler panics, e.g. to report it to an error tracker
+func (p *Prog) OnPanic(h PanicHook) {
+	p.panicHooks = append(p.panicHooks, h)
+}
+
+// recovered logs the panic, calls panic hooks and returns error which responds with 500.
+// http.ErrAbortHandler is panicked again so the http server can abort the response.
+func (p *Prog) recovered(ctx *Ctx, v interface{}) error {
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+	stack := debug.Stack()
+	ctx.Logger().Error("panic recovered", "panic", v, "stack", string(stack))
+	for _, h := range p.panicHooks {
+		h(ctx, v, stack)
+	}
+	return ErrorInternalServerError{
+		Message:  "internal server error",
+		Internal: fmt.Sprintf("panic: %v", v),
+	}
+}
@@ -0,0 +1,54 @@
+package wrap
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+)
+
+func TestRecoverHandlerPanic(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var reported interface{}
+	var stack []byte
+	p.OnPanic(func(c *Ctx, v interface{}, s []byte) {
+		reported = v
+		stack = s
+	})
+	p.Get("boom", func(c *Ctx) error {
+		panic("boom")
+	})
+	ctx := serve(p, request.GET, "boom")
+	assert.Equal(http.StatusInternalServerError, ctx.Response.Status())
+	assert.Equal(ErrorInternalServerError{Message: "internal server error", Internal: "panic: boom"}, ctx.Response.Output())
+	assert.Equal("boom", reported)
+	assert.Contains(string(stack), "recover_test.go")
+}
+
+func TestRecoverNestedCall(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Route("items", func(c *Ctx) error {
+		c.Get(func(c *Ctx) error {
+			var m map[string]string
+			m["nil"] = "map"
+			return nil
+		})
+		return nil
+	})
+	ctx := serve(p, request.GET, "items")
+	assert.Equal(http.StatusInternalServerError, ctx.Response.Status())
+}
+
+func TestRecoverSubRequest(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("boom", func(c *Ctx) error {
+		panic("boom")
+	})
+	var v interface{}
+	err := p.Call().Read("boom").Bind(&v)
+	assert.IsType(ErrorInternalServerError{}, err)
+}
@@ -0,0 +1,218 @@
+package wrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/torniker/wrap/request"
+)
+
+// list of Remote defaults
+const (
+	DefaultRemoteTimeout = 10 * time.Second
+	DefaultRemoteRetries = 2
+	DefaultRemoteBackoff = 100 * time.Millisecond
+)
+
+// Remote calls another wrap service over HTTP with the Requester API.
+// Requests with idempotent actions are retried with exponential backoff
+// on network errors and 502, 503 and 504 responses.
+type Remote struct {
+	BaseURL *url.URL
+	// Client sends the requests, its Transport can be replaced e.g. in tests
+	Client *http.Client
+	// Header is sent with every request
+	Header http.Header
+	// Timeout limits every attempt, zero means no limit
+	Timeout time.Duration
+	Retries int
+	// Backoff is the delay before the first retry, it doubles with every retry
+	Backoff time.Duration
+}
+
+// NewRemote returns client of the service at baseURL with default timeout and retries
+func NewRemote(baseURL string) (*Remote, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return &Remote{
+		BaseURL: u,
+		Client:  http.DefaultClient,
+		Header:  make(http.Header),
+		Timeout: DefaultRemoteTimeout,
+		Retries: DefaultRemoteRetries,
+		Backoff: DefaultRemoteBackoff,
+	}, nil
+}
+
+// Call helps to create new request to the remote service
+func (rm *Remote) Call() *Requester {
+	return &Requester{
+		req:     &request.Req{},
+		context: context.Background(),
+		remote:  rm,
+	}
+}
+
+// Via sends the request to the remote service, context, request id and headers set with Header
+// are kept and merged with Remote.Header while headers forwarded from the parent request are dropped
+//
+//	err := ctx.Call().Via(users).Read("users/" + id).Bind(&user)
+func (r *Requester) Via(rm *Remote) *Requester {
+	r.remote = rm
+	r.req.SetHeader(r.header.Clone())
+	return r
+}
+
+// do sends the request and decodes response into v
+func (rm *Remote) do(r *Requester, v interface{}) error {
+	var body []byte
+	if r.input != nil {
+		var err error
+		body, err = json.Marshal(r.input)
+		if err != nil {
+			return err
+		}
+	}
+	retries := 0
+	if idempotent(r.req.Action()) {
+		retries = rm.Retries
+	}
+	delay := rm.Backoff
+	for attempt := 0; ; attempt++ {
+		status, resBody, err := rm.send(r, body)
+		retry := err != nil || status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+		if !retry || attempt >= retries || r.context.Err() != nil {
+			if err != nil {
+				return err
+			}
+			return decodeRemote(status, resBody, v)
+		}
+		select {
+		case <-r.context.Done():
+			return r.context.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// send makes a single attempt and returns response status and body
+func (rm *Remote) send(r *Requester, body []byte) (int, []byte, error) {
+	c := r.context
+	if rm.Timeout > 0 {
+		var cancel context.CancelFunc
+		c, cancel = context.WithTimeout(c, rm.Timeout)
+		defer cancel()
+	}
+	u := rm.BaseURL.ResolveReference(&url.URL{
+		Path:     strings.TrimPrefix(r.req.Path().URL().Path, "/"),
+		RawQuery: url.Values(r.req.Flags()).Encode(),
+	})
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(c, method(r.req.Action()), u.String(), reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	for key, vals := range rm.Header {
+		req.Header[key] = vals
+	}
+	for key, vals := range r.req.Header() {
+		req.Header[key] = vals
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.id != "" {
+		req.Header.Set(RequestIDHeader, r.id)
+	}
+	client := rm.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, resBody, nil
+}
+
+// decodeRemote unwraps ResJSON envelope into v or maps error body to typed error
+func decodeRemote(status int, body []byte, v interface{}) error {
+	if status >= http.StatusMultipleChoices {
+		var e struct {
+			Message string      `json:"message"`
+			Errors  FieldErrors `json:"errors"`
+		}
+		if json.Unmarshal(body, &e) != nil {
+			e.Message = strings.TrimSpace(string(body))
+		}
+		if status == http.StatusUnprocessableEntity {
+			return ErrorUnprocessableEntity{Errors: e.Errors}
+		}
+		return statusError(status, e.Message)
+	}
+	if v == nil || len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var env map[string]json.RawMessage
+	if json.Unmarshal(body, &env) == nil {
+		if data, ok := env["data"]; ok && len(env) == 1 {
+			body = data
+		}
+	}
+	err := json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("remote: invalid response body: %w", err)
+	}
+	return nil
+}
+
+// method returns HTTP method of the action
+func method(a request.Action) string {
+	switch a {
+	case request.POST:
+		return http.MethodPost
+	case request.GET:
+		return http.MethodGet
+	case request.PUT:
+		return http.MethodPut
+	case request.PATCH:
+		return http.MethodPatch
+	case request.DELETE:
+		return http.MethodDelete
+	case request.OPTIONS:
+		return http.MethodOptions
+	case request.HEAD:
+		return http.MethodHead
+	}
+	return a.String()
+}
+
+func idempotent(a request.Action) bool {
+	switch a {
+	case request.GET, request.HEAD, request.PUT, request.DELETE, request.OPTIONS:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package wrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemote(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Validator("even", evenValidator)
+	p.Get("items/:id", func(c *Ctx) error {
+		return c.JSON(bindItem{Name: c.Param("id") + c.Request.Path().URL().Query().Get("suffix"), Qty: 1})
+	})
+	p.Post("items", func(c *Ctx) error {
+		var in bindItem
+		err := c.Bind(&in)
+		if err != nil {
+			return err
+		}
+		return c.JSON(in)
+	})
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	rm, err := NewRemote(srv.URL)
+	assert.NoError(err)
+
+	var item bindItem
+	assert.NoError(rm.Call().Read("items/pen").Flags(map[string][]string{"suffix": {"s"}}).Bind(&item))
+	assert.Equal(bindItem{Name: "pens", Qty: 1}, item)
+
+	assert.NoError(rm.Call().Create("items").Input(bindItem{Name: "cup", Qty: 2}).Bind(&item))
+	assert.Equal(bindItem{Name: "cup", Qty: 2}, item)
+
+	err = rm.Call().Create("items").Input(bindItem{Name: "cup"}).Bind(&item)
+	assert.Equal(ErrorUnprocessableEntity{Errors: FieldErrors{{Path: []string{"qty"}, Message: "must be at least 1"}}}, err)
+
+	err = rm.Call().Read("missing").Bind(&item)
+	assert.IsType(ErrorStatusNotFound{}, err)
+
+	err = rm.Call().Delete("items/pen").Bind(nil)
+	assert.IsType(ErrorMethodNotAllowed{}, err)
+}
+
+func TestRemoteRetries(t *testing.T) {
+	assert := assert.New(t)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal("req-1", r.Header.Get(RequestIDHeader))
+		w.Write([]byte(`{"data":"ok"}`))
+	}))
+	defer srv.Close()
+	rm, _ := NewRemote(srv.URL)
+	rm.Backoff = time.Millisecond
+	var out string
+	r := rm.Call().Read("status")
+	r.id = "req-1"
+	assert.NoError(r.Bind(&out))
+	assert.Equal("ok", out)
+	assert.Equal(int32(3), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	err := rm.Call().Create("status").Bind(&out)
+	assert.IsType(ErrorServiceUnavailable{}, err)
+	assert.Equal(int32(1), atomic.LoadInt32(&calls))
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestRemoteTransportAndTimeout(t *testing.T) {
+	assert := assert.New(t)
+	rm, _ := NewRemote("http://users.internal/v1")
+	rm.Retries = 0
+	rm.Timeout = 10 * time.Millisecond
+	rm.Header.Set("Authorization", "Bearer t")
+	var got *http.Request
+	rm.Client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})}
+	err := rm.Call().Patch("users/1").Input(map[string]string{"name": "ann"}).Bind(nil)
+	assert.Error(err)
+	if assert.NotNil(got) {
+		assert.Equal(http.MethodPatch, got.Method)
+		assert.Equal("http://users.internal/v1/users/1", got.URL.String())
+		assert.Equal("Bearer t", got.Header.Get("Authorization"))
+		assert.Equal("application/json", got.Header.Get("Content-Type"))
+	}
+}
+
+func TestRemoteViaHeaders(t *testing.T) {
+	assert := assert.New(t)
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Write([]byte(`{"data":null}`))
+	}))
+	defer srv.Close()
+	rm, err := NewRemote(srv.URL)
+	assert.NoError(err)
+	rm.Header.Set("X-Service", "app")
+	rm.Header.Set("X-Trace", "remote")
+
+	p := New()
+	p.Get("proxy", func(c *Ctx) error {
+		return c.Call().Header("X-Trace", "before").Via(rm).Header("X-After", "after").Read("items").Bind(nil)
+	})
+	w := serveRequest(p, http.MethodGet, "/proxy", nil, map[string]string{"Authorization": "Bearer t"})
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("app", got.Get("X-Service"))
+	assert.Equal("before", got.Get("X-Trace"))
+	assert.Equal("after", got.Get("X-After"))
+	assert.Empty(got.Get("Authorization"))
+}
@@ -0,0 +1,312 @@
+package wrap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/torniker/wrap/logger"
+	"github.com/torniker/wrap/request"
+	"golang.org/x/term"
+)
+
+// DefaultHistorySize is the number of commands kept in REPL history
+const DefaultHistorySize = 1000
+
+// ANSI colors of REPL output, they are disabled by NO_COLOR environment variable
+const (
+	colorKey     = "\x1b[34m"
+	colorString  = "\x1b[32m"
+	colorNumber  = "\x1b[36m"
+	colorLiteral = "\x1b[35m"
+	colorError   = "\x1b[31m"
+	colorReset   = "\x1b[0m"
+)
+
+// builtins are commands handled by the CLI session
+var builtins = []string{"help", "set", "unset", "vars", "expect", "exit"}
+
+// REPL runs interactive CLI on the terminal. Lines are edited in place, history is kept in p.HistoryFile,
+// tab completes actions and route paths, JSON responses are indented and colored and variables
+// stored with set are used in commands as $name.
+func (p *Prog) REPL(rw io.ReadWriter) error {
+	t := term.NewTerminal(rw, "--> ")
+	h, err := loadHistory(p.HistoryFile, DefaultHistorySize)
+	if err != nil {
+		return err
+	}
+	t.History = h
+	s := p.newSession(t)
+	// terminal in raw mode does not return the carriage on new line, log entries written by the terminal
+	// do not break the edited line
+	s.console = p.newConsole(t, t)
+	prevDefault := logger.Default()
+	logger.SetDefault(prevDefault.WithSinks(logger.NewSink(t, logger.TextEncoder{})))
+	defer logger.SetDefault(prevDefault)
+	s.pretty = true
+	s.color = os.Getenv("NO_COLOR") == ""
+	s.stdin = func() io.Reader {
+		var b strings.Builder
+		t.SetPrompt("")
+		for {
+			line, err := t.ReadLine()
+			if err != nil || line == "." {
+				return strings.NewReader(b.String())
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	t.AutoCompleteCallback = s.complete
+	err = s.run(func() (string, error) {
+		line, err := t.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		return line + "\n", nil
+	}, t.SetPrompt)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// complete completes action, builtin or route path before the cursor when tab is pressed
+func (s *session) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+	head := line[:pos]
+	start := strings.LastIndexAny(head, " \t") + 1
+	word := head[start:]
+	var candidates []string
+	switch fields := strings.Fields(head[:start]); len(fields) {
+	case 0:
+		candidates = append(append(builtins, actionNames()...), s.paths(word)...)
+	case 1:
+		if a := request.NewActionFromString(fields[0]); a.IsValid() {
+			candidates = s.paths(word)
+		}
+	}
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+	completion := matches[0]
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, completion) {
+			completion = completion[:len(completion)-1]
+		}
+	}
+	if len(matches) == 1 && !strings.HasSuffix(completion, "/") {
+		completion += " "
+	}
+	return head[:start] + completion + line[pos:], start + len(completion), true
+}
+
+// paths returns next segments of routes matching the path typed so far, params match any segment
+func (s *session) paths(word string) []string {
+	typed := strings.Split(strings.TrimPrefix(word, "/"), "/")
+	prefix := word[:len(word)-len(typed[len(typed)-1])]
+	seen := make(map[string]bool)
+	var paths []string
+	for _, route := range s.prog.Router.Routes() {
+		segments := splitPath(route.Pattern)
+		if len(segments) < len(typed) {
+			continue
+		}
+		ok := true
+		for i, seg := range typed[:len(typed)-1] {
+			if segments[i] != seg && !strings.HasPrefix(segments[i], ":") {
+				ok = false
+				break
+			}
+		}
+		next := segments[len(typed)-1]
+		if !ok || strings.HasPrefix(next, ":") || strings.HasPrefix(next, "*") {
+			continue
+		}
+		path := prefix + next
+		if len(segments) > len(typed) {
+			path += "/"
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// output writes JSON indented and colored when it is complete, other output is written as it comes
+type output struct {
+	w      io.Writer
+	color  bool
+	json   bool
+	stream bool
+	buf    bytes.Buffer
+}
+
+func (o *output) Write(b []byte) (int, error) {
+	if !o.json && !o.stream {
+		t := bytes.TrimLeft(b, " \t\r\n")
+		switch {
+		case len(t) == 0:
+			return o.buf.Write(b)
+		case t[0] == '{' || t[0] == '[':
+			o.json = true
+		default:
+			o.stream = true
+			_, err := o.buf.WriteTo(o.w)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	if o.json {
+		return o.buf.Write(b)
+	}
+	return o.w.Write(b)
+}
+
+// Close writes buffered output
+func (o *output) Close() error {
+	if o.json && json.Valid(o.buf.Bytes()) {
+		return writeJSON(o.w, o.buf.Bytes(), o.color)
+	}
+	_, err := o.buf.WriteTo(o.w)
+	return err
+}
+
+// writeJSON writes indented JSON with keys, strings, numbers and literals colored
+func writeJSON(w io.Writer, data []byte, color bool) error {
+	var b bytes.Buffer
+	err := json.Indent(&b, data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if !color {
+		_, err = b.WriteTo(w)
+		return err
+	}
+	s := b.Bytes()
+	out := make([]byte, 0, len(s)*2)
+	paint := func(color string, token []byte) {
+		out = append(append(append(out, color...), token...), colorReset...)
+	}
+	for i := 0; i < len(s); {
+		j := i + 1
+		switch c := s[i]; {
+		case c == '"':
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j++
+			if j < len(s) && s[j] == ':' {
+				paint(colorKey, s[i:j])
+			} else {
+				paint(colorString, s[i:j])
+			}
+		case c == '-' || (c >= '0' && c <= '9'):
+			for j < len(s) && strings.IndexByte("+-.eE0123456789", s[j]) >= 0 {
+				j++
+			}
+			paint(colorNumber, s[i:j])
+		case c >= 'a' && c <= 'z':
+			for j < len(s) && s[j] >= 'a' && s[j] <= 'z' {
+				j++
+			}
+			paint(colorLiteral, s[i:j])
+		default:
+			out = append(out, c)
+		}
+		i = j
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// history keeps commands of the REPL in memory and appends them to the file
+type history struct {
+	lines []string
+	size  int
+	file  string
+}
+
+// loadHistory reads last size commands from the file, empty file keeps the history in memory only
+func loadHistory(file string, size int) (*history, error) {
+	h := &history{size: size, file: file}
+	if file == "" {
+		return h, nil
+	}
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		h.lines = append(h.lines, sc.Text())
+	}
+	if len(h.lines) > size {
+		h.lines = h.lines[len(h.lines)-size:]
+		err = os.WriteFile(file, []byte(strings.Join(h.lines, "\n")+"\n"), 0600)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h, sc.Err()
+}
+
+// Add appends the command to the history
+func (h *history) Add(entry string) {
+	if strings.TrimSpace(entry) == "" {
+		return
+	}
+	h.lines = append(h.lines, entry)
+	if len(h.lines) > h.size {
+		h.lines = h.lines[len(h.lines)-h.size:]
+	}
+	if h.file == "" {
+		return
+	}
+	f, err := os.OpenFile(h.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(entry + "\n")
+}
+
+// Len returns the number of commands
+func (h *history) Len() int {
+	return len(h.lines)
+}
+
+// At returns command, 0 is the most recent one
+func (h *history) At(idx int) string {
+	return h.lines[len(h.lines)-1-idx]
+}
+
+// defaultHistoryFile returns history file named after the program in the home directory
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "."+filepath.Base(os.Args[0])+"_history")
+}
@@ -0,0 +1,134 @@
+package wrap
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestREPL(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("NO_COLOR", "1")
+	p := New()
+	p.HistoryFile = filepath.Join(t.TempDir(), "history")
+	var got []string
+	p.Post("users", func(c *Ctx) error {
+		return c.JSON(map[string]int{"id": 42})
+	})
+	p.Get("users/:id", func(c *Ctx) error {
+		got = append(got, c.Param("id")+" "+c.Header("X-Token"))
+		return c.NotFound()
+	})
+	p.Describe("users/:id", "shows user")
+	in := "set token=\"a b\"\r" +
+		"post users {\r}\r" +
+		"get users/$last.data.id -H 'X-Token: $token'\r" +
+		"help\r" +
+		"exit\r"
+	var out bytes.Buffer
+	err := p.REPL(struct {
+		io.Reader
+		io.Writer
+	}{strings.NewReader(in), &out})
+	assert.NoError(err)
+	assert.Equal([]string{"42 a b"}, got)
+	assert.Contains(out.String(), "{\r\n  \"data\": {\r\n    \"id\": 42\r\n  }\r\n}")
+	assert.Contains(out.String(), "404 Not Found")
+	assert.Regexp(`get\s+users/:id\s+shows user`, out.String())
+
+	b, err := os.ReadFile(p.HistoryFile)
+	assert.NoError(err)
+	assert.Equal(strings.Replace(in, "\r", "\n", -1), string(b))
+}
+
+func TestREPLLogger(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Post("seed", func(c *Ctx) error {
+		c.Logger().Info("seeded")
+		return c.JSON("ok")
+	})
+	prev := p.Logger
+	var out bytes.Buffer
+	err := p.REPL(struct {
+		io.Reader
+		io.Writer
+	}{strings.NewReader("post seed\rexit\r"), &out})
+	assert.NoError(err)
+	assert.Regexp(`INFO +seeded.*\r\n`, out.String())
+	assert.Regexp(`access.*path=/?seed.*\r\n`, out.String())
+	assert.Equal(prev, p.Logger)
+}
+
+func TestREPLComplete(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	h := func(c *Ctx) error { return nil }
+	p.Get("users", h)
+	p.Get("users/:id/posts", h)
+	p.Get("users/:id/photos", h)
+	s := p.newSession(io.Discard)
+	complete := func(line string) string {
+		newLine, _, ok := s.complete(line, len(line), '\t')
+		if !ok {
+			return line
+		}
+		return newLine
+	}
+	assert.Equal("post ", complete("pos"))
+	assert.Equal("expect ", complete("exp"))
+	assert.Equal("get users", complete("get us"))
+	assert.Equal("get users/42/p", complete("get users/42/"))
+	assert.Equal("get users/42/posts ", complete("get users/42/po"))
+	assert.Equal("unknown us", complete("unknown us"))
+}
+
+func TestSessionVars(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var out bytes.Buffer
+	s := p.newSession(&out)
+	s.vars["last"] = map[string]interface{}{"items": []interface{}{"a", "b"}}
+	parse := func(line string) (*Command, error) {
+		return parseCommand(&scanner{s: line, vars: s.variable})
+	}
+	cmd, err := parse(`get ${last.items.1}/x \$last`)
+	assert.NoError(err)
+	assert.Equal("b/x", cmd.Path)
+	assert.Equal("$last", cmd.Body)
+	cmd, err = parse(`post items $last.items`)
+	assert.NoError(err)
+	assert.Equal(`["a","b"]`, cmd.Body)
+	_, err = parse("get $missing")
+	assert.EqualError(err, "unknown variable $missing")
+	_, err = parse("get $last.items.5")
+	assert.EqualError(err, "invalid variable $last.items.5: index 5 out of range")
+
+	// values are never split into words, flags or headers and are escaped in JSON strings
+	s.vars["name"] = `John --x=1 "q" 'a'`
+	s.vars["flag"] = "--y=2"
+	cmd, err = parse(`post users/$name --n="$name" -H 'X-Name: $name' $flag`)
+	assert.NoError(err)
+	assert.Equal(`users/John --x=1 "q" 'a'`, cmd.Path)
+	assert.Equal(map[string][]string{"n": {`John --x=1 "q" 'a'`}}, cmd.Flags)
+	assert.Equal(`John --x=1 "q" 'a'`, cmd.Header.Get("X-Name"))
+	assert.Equal("--y=2", cmd.Body)
+	cmd, err = parse(`post users {"name": "$name", "items": $last.items}`)
+	assert.NoError(err)
+	assert.JSONEq(`{"name": "John --x=1 \"q\" 'a'", "items": ["a","b"]}`, cmd.Body)
+	assert.EqualError(s.exec("set 1x=2"), `invalid variable "1x=2", expected set name=value`)
+	assert.NoError(s.exec(`set body={"a": [1, 2]}`))
+	assert.Equal(`{"a": [1, 2]}`, s.vars["body"])
+}
+
+func TestWriteJSON(t *testing.T) {
+	assert := assert.New(t)
+	var b bytes.Buffer
+	assert.NoError(writeJSON(&b, []byte(`{"a":"x\"y","n":-1.5,"t":true}`), true))
+	assert.Equal("{\n  \x1b[34m\"a\"\x1b[0m: \x1b[32m\"x\\\"y\"\x1b[0m,\n  \x1b[34m\"n\"\x1b[0m: \x1b[36m-1.5\x1b[0m,\n  \x1b[34m\"t\"\x1b[0m: \x1b[35mtrue\x1b[0m\n}", b.String())
+}
@@ -0,0 +1,166 @@
+package request
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"strings"
+)
+
+// ErrBodyTooLarge is returned while reading body which exceeds the limit set by LimitBody
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// ErrNotMultipart is returned when multipart body is requested but the content type is different
+var ErrNotMultipart = errors.New("request content type is not multipart/form-data")
+
+// maxBytesReader reads at most n bytes from r and returns ErrBodyTooLarge if there are more
+type maxBytesReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+// MaxBytesReader limits r to n bytes, reading past the limit returns ErrBodyTooLarge.
+// It closes r on Close if r is an io.Closer.
+func MaxBytesReader(r io.Reader, n int64) io.ReadCloser {
+	return &maxBytesReader{r: r, n: n}
+}
+
+func (l *maxBytesReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// read one byte more than allowed to find out if body exceeds the limit
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) <= l.n {
+		l.n -= int64(n)
+		l.err = err
+		return n, err
+	}
+	n = int(l.n)
+	l.n = 0
+	l.err = ErrBodyTooLarge
+	return n, l.err
+}
+
+func (l *maxBytesReader) Close() error {
+	if c, ok := l.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// stream is body of CLI and sub-requests with optional size limit and lazily parsed multipart form
+type stream struct {
+	src       io.Reader
+	body      io.Reader
+	form      *multipart.Form
+	maxMemory int64
+}
+
+func newStream(r io.Reader) *stream {
+	return &stream{src: r, body: r}
+}
+
+// reader returns body reader or nil if there is none
+func (in *stream) reader() io.Reader {
+	if in == nil {
+		return nil
+	}
+	return in.body
+}
+
+func (in *stream) limit(n int64) {
+	if in == nil || in.src == nil {
+		return
+	}
+	in.body = MaxBytesReader(in.src, n)
+}
+
+func (in *stream) setMaxMemory(n int64) {
+	if in == nil {
+		return
+	}
+	in.maxMemory = n
+}
+
+// bind decodes body into v, multipart form is kept until close so uploaded files can be read
+func (in *stream) bind(contentType string, v interface{}) error {
+	if in.reader() == nil {
+		return io.EOF
+	}
+	if isMultipart(contentType) {
+		form, err := in.multipartForm(contentType, maxMemory(in.maxMemory))
+		if err != nil {
+			return err
+		}
+		return BindForm(form, v)
+	}
+	return Decode(contentType, in.body, v)
+}
+
+func (in *stream) multipartReader(contentType string) (*multipart.Reader, error) {
+	if in.reader() == nil {
+		return nil, ErrNotMultipart
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return nil, ErrNotMultipart
+	}
+	return multipart.NewReader(in.body, params["boundary"]), nil
+}
+
+// multipartForm parses multipart body once, files bigger than maxMemory are spooled to temporary files
+func (in *stream) multipartForm(contentType string, maxMemory int64) (*multipart.Form, error) {
+	if in != nil && in.form != nil {
+		return in.form, nil
+	}
+	mr, err := in.multipartReader(contentType)
+	if err != nil {
+		return nil, err
+	}
+	in.form, err = mr.ReadForm(maxMemory)
+	return in.form, err
+}
+
+// close removes temporary files of multipart form and closes the source
+func (in *stream) close() error {
+	if in == nil {
+		return nil
+	}
+	var err error
+	if in.form != nil {
+		err = in.form.RemoveAll()
+	}
+	if c, ok := in.src.(io.Closer); ok {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// OpenInput returns reader of CLI input, input starting with @ is a path of the local file
+// which is streamed as the body, e.g. @./file.bin
+func OpenInput(s string) (io.Reader, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "@") {
+		f, err := os.Open(strings.TrimPrefix(s, "@"))
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	if s == "" {
+		return nil, nil
+	}
+	return strings.NewReader(s), nil
+}
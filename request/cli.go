@@ -1,26 +1,30 @@
 package request
 
 import (
-	"encoding/json"
 	"io"
+	"mime/multipart"
+	"net/http"
 	"net/url"
 )
 
-// NewCLI create an instance of CLI request
+// NewCLI create an instance of CLI request, query params of the url become flags
 func NewCLI(a Action, url *url.URL, input io.Reader) *CLI {
 	return &CLI{
 		action: a,
 		path:   NewPath(url),
-		input:  input,
-		flags:  make(map[string][]string),
+		body:   newStream(input),
+		flags:  withQuery(nil, url),
+		header: make(http.Header),
 	}
 }
 
 // CLI is type for CLI requests
 type CLI struct {
+	params
+	header http.Header
 	action Action
 	path   *Path
-	input  io.Reader
+	body   *stream
 	flags  map[string][]string
 }
 
@@ -29,10 +33,40 @@ func (c CLI) Action() Action {
 	return c.action
 }
 
-// Bind returns request body
+// Bind decodes input into v according to the content-type flag, JSON by default
 func (c CLI) Bind(v interface{}) error {
-	decoder := json.NewDecoder(c.input)
-	return decoder.Decode(&v)
+	return c.body.bind(contentType(c.flags), v)
+}
+
+// Body returns raw input, it is nil when there is none
+func (c CLI) Body() io.Reader {
+	return c.body.reader()
+}
+
+// LimitBody limits input to n bytes, reading past the limit returns ErrBodyTooLarge
+func (c *CLI) LimitBody(n int64) {
+	c.body.limit(n)
+}
+
+// SetMaxMemory sets the number of bytes of multipart input Bind keeps in memory, the rest of files is stored on disk
+func (c *CLI) SetMaxMemory(n int64) {
+	c.body.setMaxMemory(n)
+}
+
+// MultipartReader returns reader of multipart input parts, the content type is taken from the content-type flag
+func (c CLI) MultipartReader() (*multipart.Reader, error) {
+	return c.body.multipartReader(contentType(c.flags))
+}
+
+// MultipartForm parses multipart input once, files bigger than maxMemory are spooled to temporary files
+// which are removed on Close
+func (c CLI) MultipartForm(maxMemory int64) (*multipart.Form, error) {
+	return c.body.multipartForm(contentType(c.flags), maxMemory)
+}
+
+// Close removes temporary files of multipart input and closes the input
+func (c CLI) Close() error {
+	return c.body.close()
 }
 
 // Flags returns cli flags
@@ -44,3 +78,27 @@ func (c CLI) Flags() map[string][]string {
 func (c CLI) Path() *Path {
 	return c.path
 }
+
+// Header returns headers given to the command
+func (c CLI) Header() http.Header {
+	return c.header
+}
+
+// SetFlags merges flags into flags given in the url query, the flags take precedence
+func (c *CLI) SetFlags(flags map[string][]string) *CLI {
+	for key, vals := range flags {
+		c.flags[key] = vals
+	}
+	return c
+}
+
+// SetHeader sets headers of the command
+func (c *CLI) SetHeader(h http.Header) *CLI {
+	c.header = h
+	return c
+}
+
+// Cookies returns cookies of Cookie header
+func (c CLI) Cookies() []*http.Cookie {
+	return readCookies(c.header)
+}
@@ -0,0 +1,252 @@
+package request
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/shamaton/msgpack/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ContentTypeFlag is the flag which sets media type of CLI and sub-request input, e.g. --content-type=yaml
+const ContentTypeFlag = "content-type"
+
+// DefaultMaxMemory is the number of bytes of multipart body kept in memory, the rest of files is stored on disk
+const DefaultMaxMemory = 32 << 20
+
+// maxMemory returns n or DefaultMaxMemory when n is not positive
+func maxMemory(n int64) int64 {
+	if n <= 0 {
+		return DefaultMaxMemory
+	}
+	return n
+}
+
+// ErrUnsupportedMediaType is returned by Bind when there is no decoder for the media type
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// Decoder decodes request body into v, params are the media type parameters such as multipart boundary
+type Decoder interface {
+	Decode(r io.Reader, params map[string]string, v interface{}) error
+}
+
+// JSONDecoder decodes JSON body
+type JSONDecoder struct{}
+
+// Decode reads JSON from r into v
+func (JSONDecoder) Decode(r io.Reader, params map[string]string, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// XMLDecoder decodes XML body, the name of root element is not checked
+type XMLDecoder struct{}
+
+// Decode reads XML from r into v
+func (XMLDecoder) Decode(r io.Reader, params map[string]string, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// YAMLDecoder decodes YAML body
+type YAMLDecoder struct{}
+
+// Decode reads YAML from r into v
+func (YAMLDecoder) Decode(r io.Reader, params map[string]string, v interface{}) error {
+	return yaml.NewDecoder(r).Decode(v)
+}
+
+// MsgpackDecoder decodes MessagePack body
+type MsgpackDecoder struct{}
+
+// Decode reads MessagePack from r into v
+func (MsgpackDecoder) Decode(r io.Reader, params map[string]string, v interface{}) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(b) == 0 {
+		return io.EOF
+	}
+	return msgpack.Unmarshal(b, v)
+}
+
+// FormDecoder decodes URL encoded form into struct fields, see BindForm
+type FormDecoder struct{}
+
+// Decode reads URL encoded form from r into v
+func (FormDecoder) Decode(r io.Reader, params map[string]string, v interface{}) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return err
+	}
+	return BindForm(&multipart.Form{Value: values}, v)
+}
+
+// MultipartDecoder decodes multipart form into struct fields, see BindForm.
+// Files bigger than MaxMemory are stored in temporary files which are removed before Decode returns,
+// so only files kept in memory can be opened afterwards. Requests bind multipart bodies themselves
+// and keep the files until they are served.
+type MultipartDecoder struct {
+	MaxMemory int64
+}
+
+// Decode reads multipart form from r into v
+func (d MultipartDecoder) Decode(r io.Reader, params map[string]string, v interface{}) error {
+	boundary := params["boundary"]
+	if boundary == "" {
+		return errors.New("multipart: boundary is missing")
+	}
+	form, err := multipart.NewReader(r, boundary).ReadForm(maxMemory(d.MaxMemory))
+	if err != nil {
+		return err
+	}
+	defer form.RemoveAll()
+	return BindForm(form, v)
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]Decoder{
+		"application/json":                  JSONDecoder{},
+		"application/xml":                   XMLDecoder{},
+		"text/xml":                          XMLDecoder{},
+		"application/yaml":                  YAMLDecoder{},
+		"application/x-yaml":                YAMLDecoder{},
+		"text/yaml":                         YAMLDecoder{},
+		"application/msgpack":               MsgpackDecoder{},
+		"application/x-msgpack":             MsgpackDecoder{},
+		"application/x-www-form-urlencoded": FormDecoder{},
+		"multipart/form-data":               MultipartDecoder{},
+	}
+	// shortNames are accepted in ContentTypeFlag instead of full media types
+	shortNames = map[string]string{
+		"json":      "application/json",
+		"xml":       "application/xml",
+		"yaml":      "application/yaml",
+		"msgpack":   "application/msgpack",
+		"form":      "application/x-www-form-urlencoded",
+		"multipart": "multipart/form-data",
+	}
+)
+
+// RegisterDecoder adds decoder for the media type or replaces the existing one
+func RegisterDecoder(mediaType string, d Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[strings.ToLower(mediaType)] = d
+}
+
+// Decode decodes r into v with decoder registered for the content type, empty content type is decoded as JSON.
+// It returns error wrapping ErrUnsupportedMediaType when there is no such decoder.
+func Decode(contentType string, r io.Reader, v interface{}) error {
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	if mediaType, ok := shortNames[strings.ToLower(contentType)]; ok {
+		contentType = mediaType
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsupportedMediaType, contentType)
+	}
+	decodersMu.RLock()
+	d, ok := decoders[mediaType]
+	decodersMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnsupportedMediaType, mediaType)
+	}
+	return d.Decode(r, params, v)
+}
+
+func isMultipart(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// contentType returns the first value of ContentTypeFlag
+func contentType(flags map[string][]string) string {
+	if vals := flags[ContentTypeFlag]; len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// BindForm sets fields of struct v from form values and files.
+// Fields are matched by form tag, JSON name or field name in that order,
+// fields of type *multipart.FileHeader and []*multipart.FileHeader receive uploaded files.
+func BindForm(form *multipart.Form, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("bind: expected non-nil pointer, got %T", v)
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("bind: form can not be decoded into %T", v)
+	}
+	return bindForm(form, rv)
+}
+
+func bindForm(form *multipart.Form, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+		if f.Anonymous && fv.Kind() == reflect.Struct {
+			err := bindForm(form, fv)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name := formName(f)
+		if name == "-" {
+			continue
+		}
+		switch {
+		case f.Type == fileHeaderType:
+			if files := form.File[name]; len(files) > 0 {
+				fv.Set(reflect.ValueOf(files[0]))
+			}
+		case f.Type == reflect.SliceOf(fileHeaderType):
+			if files := form.File[name]; len(files) > 0 {
+				fv.Set(reflect.ValueOf(files))
+			}
+		default:
+			if vals := form.Value[name]; len(vals) > 0 {
+				err := SetValues(fv, vals)
+				if err != nil {
+					return fmt.Errorf("%v: %v", name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func formName(f reflect.StructField) string {
+	if name, ok := f.Tag.Lookup("form"); ok {
+		return name
+	}
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name != "" {
+		return name
+	}
+	return f.Name
+}
@@ -1,7 +1,8 @@
 package request
 
 import (
-	"encoding/json"
+	"io"
+	"mime/multipart"
 	"net/http"
 )
 
@@ -10,46 +11,102 @@ func NewHTTP(r *http.Request) *HTTP {
 	return &HTTP{
 		path: NewPath(r.URL),
 		req:  r,
+		src:  r.Body,
 	}
 }
 
 // HTTP is type for HTTP requests
 type HTTP struct {
+	params
 	path *Path
 	req  *http.Request
+	src  io.ReadCloser
+	// maxMemory is the number of bytes of multipart body Bind keeps in memory
+	maxMemory int64
 }
 
-// Action returns method
+// Action returns action of the method, methods of custom actions are routed to them
 func (h HTTP) Action() Action {
-	switch h.req.Method {
-	case "GET":
-		return GET
-	case "POST":
-		return POST
-	case "PUT":
-		return PUT
-	case "DELETE":
-		return DELETE
-	case "OPTIONS":
-		return OPTIONS
-	}
-	return 0
+	return NewActionFromString(h.req.Method)
 }
 
-// Bind build object from input
+// Bind decodes body into v according to Content-Type header.
+// Multipart forms are parsed by the http request so uploaded files are removed once it is served.
 func (h HTTP) Bind(v interface{}) error {
-	decoder := json.NewDecoder(h.req.Body)
-	return decoder.Decode(&v)
+	if h.req.Body == nil || h.req.Body == http.NoBody {
+		return io.EOF
+	}
+	contentType := h.req.Header.Get("Content-Type")
+	if isMultipart(contentType) {
+		form, err := h.MultipartForm(maxMemory(h.maxMemory))
+		if err != nil {
+			return err
+		}
+		return BindForm(form, v)
+	}
+	return Decode(contentType, h.req.Body, v)
 }
 
-// Flags returns request Query params
-func (h HTTP) Flags() map[string][]string {
-	flags := h.req.URL.Query()
-	// TODO: handle conflicts
-	for key, val := range h.req.Header {
-		flags[key] = val
+// Body returns raw request body
+func (h HTTP) Body() io.Reader {
+	return h.req.Body
+}
+
+// LimitBody limits body to n bytes, reading past the limit returns ErrBodyTooLarge
+func (h *HTTP) LimitBody(n int64) {
+	if h.src == nil || h.src == http.NoBody {
+		return
 	}
-	return flags
+	h.req.Body = MaxBytesReader(h.src, n)
+}
+
+// SetMaxMemory sets the number of bytes of multipart body Bind keeps in memory, the rest of files is stored on disk
+func (h *HTTP) SetMaxMemory(n int64) {
+	h.maxMemory = n
+}
+
+// MultipartReader returns reader of multipart body parts for streaming uploads
+func (h HTTP) MultipartReader() (*multipart.Reader, error) {
+	r, err := h.req.MultipartReader()
+	if err == http.ErrNotMultipart {
+		return nil, ErrNotMultipart
+	}
+	return r, err
+}
+
+// MultipartForm parses multipart body once, files bigger than maxMemory are spooled to temporary files
+// which are removed when the request is served
+func (h HTTP) MultipartForm(maxMemory int64) (*multipart.Form, error) {
+	err := h.req.ParseMultipartForm(maxMemory)
+	if err == http.ErrNotMultipart {
+		return nil, ErrNotMultipart
+	}
+	return h.req.MultipartForm, err
+}
+
+// Flags returns request query params
+func (h HTTP) Flags() map[string][]string {
+	return h.req.URL.Query()
+}
+
+// Header returns request headers
+func (h HTTP) Header() http.Header {
+	return h.req.Header
+}
+
+// Cookies returns request cookies
+func (h HTTP) Cookies() []*http.Cookie {
+	return h.req.Cookies()
+}
+
+// Raw returns underlying http request
+func (h HTTP) Raw() *http.Request {
+	return h.req
+}
+
+// RemoteAddr returns network address of the client
+func (h HTTP) RemoteAddr() string {
+	return h.req.RemoteAddr
 }
 
 // Path returns request path
@@ -0,0 +1,40 @@
+package request
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// params holds path params matched by the router, it is embedded by requests
+type params struct {
+	params map[string]string
+}
+
+// Params returns path params matched by the router
+func (p params) Params() map[string]string {
+	return p.params
+}
+
+// SetParams sets path params, it is called by the router
+func (p *params) SetParams(v map[string]string) {
+	p.params = v
+}
+
+// readCookies parses Cookie headers
+func readCookies(h http.Header) []*http.Cookie {
+	return (&http.Request{Header: h}).Cookies()
+}
+
+// withQuery returns flags merged with query params of u, flags take precedence
+func withQuery(flags map[string][]string, u *url.URL) map[string][]string {
+	merged := make(map[string][]string)
+	if u != nil {
+		for key, vals := range u.Query() {
+			merged[key] = vals
+		}
+	}
+	for key, vals := range flags {
+		merged[key] = vals
+	}
+	return merged
+}
@@ -2,10 +2,12 @@ package request
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"mime/multipart"
+	"net/http"
 	"net/url"
-
-	"github.com/torniker/wrap/logger"
+	"reflect"
 )
 
 // NewRequest create an instance of sub request
@@ -13,18 +15,20 @@ func NewRequest(a Action, url *url.URL, input io.Reader) *Req {
 	return &Req{
 		action: a,
 		path:   NewPath(url),
-		input:  input,
+		body:   newStream(input),
 		flags:  make(map[string][]string),
 	}
 }
 
 // Req is type for sub-requests
 type Req struct {
+	params
 	action Action
 	path   *Path
-	input  io.Reader
+	body   *stream
 	data   interface{}
 	flags  map[string][]string
+	header http.Header
 }
 
 // Action returns action
@@ -32,20 +36,66 @@ func (r Req) Action() Action {
 	return r.action
 }
 
-// Bind binds request input to pathed object
+// Bind binds request data or input to pathed object.
+// Data of the same type is assigned directly, otherwise it is converted through JSON.
+// Input is decoded according to the content-type flag, JSON by default.
 func (r Req) Bind(v interface{}) error {
-	logger.Infof("input: %v", r.data)
 	if r.data != nil {
-		v = r.data
-		return nil
+		dst := reflect.ValueOf(v)
+		if dst.Kind() != reflect.Ptr || dst.IsNil() {
+			return fmt.Errorf("bind: expected non-nil pointer, got %T", v)
+		}
+		src := reflect.Indirect(reflect.ValueOf(r.data))
+		if src.Type().AssignableTo(dst.Elem().Type()) {
+			dst.Elem().Set(src)
+			return nil
+		}
+		b, err := json.Marshal(r.data)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(b, v)
 	}
-	decoder := json.NewDecoder(r.input)
-	return decoder.Decode(&v)
+	return r.body.bind(contentType(r.flags), v)
+}
+
+// Body returns raw input, it is nil when there is none
+func (r Req) Body() io.Reader {
+	return r.body.reader()
+}
+
+// LimitBody limits input to n bytes, reading past the limit returns ErrBodyTooLarge
+func (r *Req) LimitBody(n int64) {
+	r.body.limit(n)
+}
+
+// SetMaxMemory sets the number of bytes of multipart input Bind keeps in memory, the rest of files is stored on disk
+func (r *Req) SetMaxMemory(n int64) {
+	r.body.setMaxMemory(n)
+}
+
+// MultipartReader returns reader of multipart input parts, the content type is taken from the content-type flag
+func (r Req) MultipartReader() (*multipart.Reader, error) {
+	return r.body.multipartReader(contentType(r.flags))
+}
+
+// MultipartForm parses multipart input once, files bigger than maxMemory are spooled to temporary files
+// which are removed on Close
+func (r Req) MultipartForm(maxMemory int64) (*multipart.Form, error) {
+	return r.body.multipartForm(contentType(r.flags), maxMemory)
 }
 
-// Flags returns optional params
+// Close removes temporary files of multipart input and closes the input
+func (r Req) Close() error {
+	return r.body.close()
+}
+
+// Flags returns flags merged with query params of the path
 func (r Req) Flags() map[string][]string {
-	return r.flags
+	if r.path == nil {
+		return withQuery(r.flags, nil)
+	}
+	return withQuery(r.flags, r.path.URL())
 }
 
 // Path returns url path
@@ -67,7 +117,7 @@ func (r *Req) SetPath(v *url.URL) *Req {
 
 // SetInput sets input
 func (r *Req) SetInput(v io.Reader) *Req {
-	r.input = v
+	r.body = newStream(v)
 	return r
 }
 
@@ -77,6 +127,25 @@ func (r *Req) SetData(v interface{}) *Req {
 	return r
 }
 
+// Header returns headers forwarded to the sub-request
+func (r Req) Header() http.Header {
+	if r.header == nil {
+		return http.Header{}
+	}
+	return r.header
+}
+
+// Cookies returns cookies of forwarded Cookie header
+func (r Req) Cookies() []*http.Cookie {
+	return readCookies(r.header)
+}
+
+// SetHeader sets headers
+func (r *Req) SetHeader(h http.Header) *Req {
+	r.header = h
+	return r
+}
+
 // SetFlags sets flags
 func (r *Req) SetFlags(flags map[string][]string) *Req {
 	r.flags = flags
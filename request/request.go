@@ -2,8 +2,11 @@ package request
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 )
 
 // list of request actions
@@ -13,17 +16,70 @@ const (
 	PUT
 	DELETE
 	OPTIONS
+	PATCH
+	HEAD
 )
 
+// customActions holds names of actions registered with RegisterAction
+var (
+	customMu      sync.RWMutex
+	customActions = make(map[Action]string)
+)
+
+// RegisterAction registers custom action, e.g. ARCHIVE, and returns its value.
+// Registering the same name again returns the same action.
+func RegisterAction(name string) Action {
+	name = strings.ToUpper(name)
+	customMu.Lock()
+	defer customMu.Unlock()
+	for a, n := range customActions {
+		if n == name {
+			return a
+		}
+	}
+	a := HEAD + 1 + Action(len(customActions))
+	customActions[a] = name
+	return a
+}
+
+// Actions returns built-in actions followed by custom actions in order of registration
+func Actions() []Action {
+	actions := []Action{POST, GET, PUT, DELETE, OPTIONS, PATCH, HEAD}
+	customMu.RLock()
+	defer customMu.RUnlock()
+	for i := 0; i < len(customActions); i++ {
+		actions = append(actions, HEAD+1+Action(i))
+	}
+	return actions
+}
+
+func customAction(name string) Action {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	for a, n := range customActions {
+		if n == name {
+			return a
+		}
+	}
+	return Action(0)
+}
+
 // Action type for describing request action
 type Action int
 
-// Request defines methods for request object
+// Request defines methods for request object.
+// Flags are query params of HTTP requests and flags of other transports merged with query
+// params of their path, headers, cookies and path params are kept apart from them.
 type Request interface {
 	Action() Action
 	Path() *Path
 	Bind(v interface{}) error
+	Body() io.Reader
 	Flags() map[string][]string
+	Header() http.Header
+	Cookies() []*http.Cookie
+	Params() map[string]string
+	SetParams(params map[string]string)
 }
 
 // Path defines structure for command path
@@ -47,10 +103,13 @@ func (a *Action) IsValid() bool {
 	if a == nil {
 		return false
 	}
-	if *a > 0 && *a < 5 {
+	if *a > 0 && *a <= HEAD {
 		return true
 	}
-	return false
+	customMu.RLock()
+	defer customMu.RUnlock()
+	_, ok := customActions[*a]
+	return ok
 }
 
 // NewActionFromString returns action
@@ -64,8 +123,14 @@ func NewActionFromString(a string) Action {
 		return PUT
 	case "DELETE":
 		return DELETE
+	case "PATCH":
+		return PATCH
+	case "HEAD":
+		return HEAD
+	case "OPTIONS":
+		return OPTIONS
 	}
-	return Action(0)
+	return customAction(strings.ToUpper(a))
 }
 
 // string returns string name of action
@@ -79,8 +144,16 @@ func (a Action) String() string {
 		return "PUT"
 	case DELETE:
 		return "DELETE"
+	case OPTIONS:
+		return "OPTIONS"
+	case PATCH:
+		return "PATCH"
+	case HEAD:
+		return "HEAD"
 	}
-	return ""
+	customMu.RLock()
+	defer customMu.RUnlock()
+	return customActions[a]
 }
 
 // NewPath creates path object from url
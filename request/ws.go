@@ -0,0 +1,102 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// WSMessage is request frame received over websocket
+type WSMessage struct {
+	ID     string              `json:"id"`
+	Action string              `json:"action"`
+	Path   string              `json:"path"`
+	Flags  map[string][]string `json:"flags"`
+	Header http.Header         `json:"headers"`
+	Body   json.RawMessage     `json:"body"`
+}
+
+// NewWS create an instance of websocket request, query params of the url are merged into flags
+func NewWS(a Action, url *url.URL, m WSMessage) *WS {
+	header := m.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &WS{
+		id:     m.ID,
+		action: a,
+		path:   NewPath(url),
+		flags:  withQuery(m.Flags, url),
+		header: header,
+		body:   m.Body,
+	}
+}
+
+// WS is type for websocket requests
+type WS struct {
+	params
+	header http.Header
+	id     string
+	action Action
+	path   *Path
+	flags  map[string][]string
+	body   json.RawMessage
+}
+
+// ID returns correlation id of the message
+func (w WS) ID() string {
+	return w.id
+}
+
+// Action returns action
+func (w WS) Action() Action {
+	return w.action
+}
+
+// Bind decodes message body into v
+func (w WS) Bind(v interface{}) error {
+	if len(w.body) == 0 {
+		return io.EOF
+	}
+	return json.Unmarshal(w.body, v)
+}
+
+// Body returns raw message body, it is nil when there is none
+func (w WS) Body() io.Reader {
+	if len(w.body) == 0 {
+		return nil
+	}
+	return bytes.NewReader(w.body)
+}
+
+// Header returns message headers
+func (w WS) Header() http.Header {
+	return w.header
+}
+
+// Cookies returns cookies of Cookie message header
+func (w WS) Cookies() []*http.Cookie {
+	return readCookies(w.header)
+}
+
+// Flags returns message flags
+func (w WS) Flags() map[string][]string {
+	return w.flags
+}
+
+// Path returns url path
+func (w WS) Path() *Path {
+	return w.path
+}
+
+// NewStdio create an instance of request read from stdin, frames are the same as websocket ones
+func NewStdio(a Action, url *url.URL, m WSMessage) *Stdio {
+	return &Stdio{WS: *NewWS(a, url, m)}
+}
+
+// Stdio is type for requests read from stdin
+type Stdio struct {
+	WS
+}
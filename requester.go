@@ -0,0 +1,227 @@
+package wrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/torniker/wrap/request"
+	"github.com/torniker/wrap/response"
+)
+
+// Call helps to create new request
+func (p *Prog) Call() *Requester {
+	return &Requester{
+		prog:    p,
+		req:     &request.Req{},
+		context: p.lifecycle.base,
+	}
+}
+
+// Call helps to create new sub request which inherits context, user, request id and headers of ctx
+func (ctx *Ctx) Call() *Requester {
+	r := ctx.Prog.Call()
+	r.context = ctx.Context()
+	r.user = ctx.User
+	r.id = ctx.id
+	r.console = ctx.console
+	r.batchDepth = ctx.batchDepth
+	r.req.SetHeader(ctx.Request.Header().Clone())
+	return r
+}
+
+// Requester wraps sub requests
+type Requester struct {
+	prog    *Prog
+	req     *request.Req
+	context context.Context
+	user    Userer
+	id      string
+	input   interface{}
+	remote  *Remote
+	console *console
+	err     error
+	// header holds headers set with Header which are kept by Via
+	header http.Header
+	// batchDepth is the number of batches the parent request runs in
+	batchDepth int
+}
+
+// Bind calls the request and decodes its response into v, v may be nil if the response is not needed.
+// Data wrapped in ResJSON is unwrapped and responses with non 2xx status are returned as typed errors,
+// errors returned by the handler are returned as they are.
+func (r *Requester) Bind(v interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.remote != nil {
+		return r.remote.do(r, v)
+	}
+	subCtx := r.prog.NewCtx(r.req, response.NewResponse())
+	subCtx.context = r.context
+	subCtx.User = r.user
+	subCtx.console = r.console
+	subCtx.batchDepth = r.batchDepth
+	if r.id != "" {
+		subCtx.id = r.id
+	}
+	start := time.Now()
+	err := r.prog.handle(subCtx)
+	if err != nil {
+		// the status of the returned error is only recorded in access log
+		status, _ := errorResponse(err)
+		subCtx.Response.SetStatus(status)
+	}
+	r.prog.AccessLog.record(subCtx, start)
+	if err != nil {
+		return err
+	}
+	status := subCtx.Response.Status()
+	if status >= http.StatusMultipleChoices {
+		return statusError(status, subCtx.Response.Output())
+	}
+	if v == nil {
+		return nil
+	}
+	return decodeOutput(subCtx.Response.Output(), v)
+}
+
+// statusError returns typed error matching the status, errors written by ctx.Error are returned as they are
+func statusError(status int, output interface{}) error {
+	if e, ok := output.(error); ok {
+		return e
+	}
+	message := http.StatusText(status)
+	if s, ok := output.(string); ok && s != "" {
+		message = s
+	}
+	switch status {
+	case http.StatusBadRequest:
+		return ErrorBadRequest{Message: message}
+	case http.StatusUnauthorized:
+		return ErrorUnauthorized{Message: message}
+	case http.StatusNotFound:
+		return ErrorStatusNotFound{Message: message}
+	case http.StatusMethodNotAllowed:
+		return ErrorMethodNotAllowed{Message: message}
+	case http.StatusNotAcceptable:
+		return ErrorNotAcceptable{Message: message}
+	case http.StatusRequestEntityTooLarge:
+		return ErrorPayloadTooLarge{Message: message}
+	case http.StatusUnsupportedMediaType:
+		return ErrorUnsupportedMediaType{Message: message}
+	case http.StatusInternalServerError:
+		return ErrorInternalServerError{Message: message}
+	case http.StatusServiceUnavailable:
+		return ErrorServiceUnavailable{Message: message}
+	}
+	return ErrorStatus{Status: status, Message: message}
+}
+
+// decodeOutput sets v to the response output, values of other types are converted through JSON
+func decodeOutput(out interface{}, v interface{}) error {
+	if env, ok := out.(ResJSON); ok {
+		out = env.Data
+	}
+	if out == nil {
+		return nil
+	}
+	dst := reflect.ValueOf(v)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return fmt.Errorf("bind: expected non-nil pointer, got %T", v)
+	}
+	src := reflect.ValueOf(out)
+	if src.Type().AssignableTo(dst.Elem().Type()) {
+		dst.Elem().Set(src)
+		return nil
+	}
+	if src.Kind() == reflect.Ptr && !src.IsNil() && src.Elem().Type().AssignableTo(dst.Elem().Type()) {
+		dst.Elem().Set(src.Elem())
+		return nil
+	}
+	if b, ok := out.([]byte); ok {
+		return json.Unmarshal(b, v)
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Path sets command name for the request
+func (r *Requester) path(command string) *Requester {
+	u, err := url.Parse(command)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.req.SetPath(u)
+	return r
+}
+
+// Flags sets flags for the request
+func (r *Requester) Flags(flags map[string][]string) *Requester {
+	r.req.SetFlags(flags)
+	return r
+}
+
+// Header sets header of the request
+func (r *Requester) Header(key, val string) *Requester {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+	r.header.Set(key, val)
+	h := r.req.Header()
+	h.Set(key, val)
+	r.req.SetHeader(h)
+	return r
+}
+
+// Input sets input data for request
+func (r *Requester) Input(v interface{}) *Requester {
+	r.input = v
+	r.req.SetData(v)
+	return r
+}
+
+// Action sets action and command of the request, it is used for custom actions
+func (r *Requester) Action(a request.Action, command string) *Requester {
+	r.req.SetAction(a)
+	r.path(command)
+	return r
+}
+
+// Create command
+func (r *Requester) Create(command string) *Requester {
+	return r.Action(request.POST, command)
+}
+
+// Read command
+func (r *Requester) Read(command string) *Requester {
+	return r.Action(request.GET, command)
+}
+
+// Update command
+func (r *Requester) Update(command string) *Requester {
+	return r.Action(request.PUT, command)
+}
+
+// Patch command
+func (r *Requester) Patch(command string) *Requester {
+	return r.Action(request.PATCH, command)
+}
+
+// Delete command
+func (r *Requester) Delete(command string) *Requester {
+	return r.Action(request.DELETE, command)
+}
+
+// Options command
+func (r *Requester) Options(command string) *Requester {
+	return r.Action(request.OPTIONS, command)
+}
@@ -0,0 +1,119 @@
+package wrap
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+)
+
+type testUser struct {
+	id uuid.UUID
+}
+
+func (u testUser) String() string     { return u.id.String() }
+func (u testUser) ID() uuid.UUID      { return u.id }
+func (u testUser) Can(f UserCan) bool { return f(u) }
+
+func TestRequesterBind(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("items/:id", func(c *Ctx) error {
+		return c.JSON(bindItem{Name: c.Param("id"), Qty: 1})
+	})
+	p.Get("names", func(c *Ctx) error {
+		return c.Respond([]string{"pen", "cup"})
+	})
+	var item bindItem
+	assert.NoError(p.Call().Read("items/pen").Bind(&item))
+	assert.Equal(bindItem{Name: "pen", Qty: 1}, item)
+
+	var m map[string]interface{}
+	assert.NoError(p.Call().Read("items/cup").Bind(&m))
+	assert.Equal("cup", m["name"])
+
+	var names []string
+	assert.NoError(p.Call().Read("names").Bind(&names))
+	assert.Equal([]string{"pen", "cup"}, names)
+	assert.NoError(p.Call().Read("names").Bind(nil))
+}
+
+func TestRequesterErrors(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	errPlain := errors.New("plain")
+	p.Get("invalid", func(c *Ctx) error {
+		return c.BadRequest("invalid id")
+	})
+	p.Get("conflict", func(c *Ctx) error {
+		c.Response.SetStatus(http.StatusConflict)
+		return c.Response.Write("already exists")
+	})
+	p.Get("plain", func(c *Ctx) error {
+		return errPlain
+	})
+	err := p.Call().Read("invalid").Bind(nil)
+	if assert.IsType(ErrorBadRequest{}, err) {
+		assert.Equal("invalid id", err.Error())
+	}
+
+	err = p.Call().Read("missing").Bind(nil)
+	assert.IsType(ErrorStatusNotFound{}, err)
+
+	err = p.Call().Update("invalid").Bind(nil)
+	assert.IsType(ErrorMethodNotAllowed{}, err)
+
+	err = p.Call().Read("conflict").Bind(nil)
+	assert.Equal(ErrorStatus{Status: http.StatusConflict, Message: "already exists"}, err)
+
+	err = p.Call().Read("plain").Bind(nil)
+	assert.Equal(errPlain, err)
+}
+
+func TestRequesterPropagation(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	user := testUser{id: uuid.Must(uuid.NewV4())}
+	var inner *Ctx
+	p.Handle(request.PATCH, "inner", func(c *Ctx) error {
+		inner = c
+		c.NoContent()
+		return nil
+	})
+	p.Get("outer", func(c *Ctx) error {
+		c.User = user
+		return c.Call().Patch("inner").Bind(nil)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/outer", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	r.Header.Set("X-Trace", "abc")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.Equal(http.StatusOK, w.Code)
+	if assert.NotNil(inner) {
+		assert.Equal(user, inner.User)
+		assert.Equal("req-1", inner.RequestID())
+		assert.Equal("abc", inner.Request.Header().Get("X-Trace"))
+	}
+}
+
+func TestRequesterCustomAction(t *testing.T) {
+	assert := assert.New(t)
+	archive := request.RegisterAction("archive")
+	assert.Equal(archive, request.RegisterAction("ARCHIVE"))
+	assert.Equal(archive, request.NewActionFromString("Archive"))
+	assert.Equal("ARCHIVE", archive.String())
+	assert.True(archive.IsValid())
+	p := New()
+	p.Handle(archive, "items/:id", func(c *Ctx) error {
+		return c.JSON(c.Param("id") + " archived")
+	})
+	var out string
+	assert.NoError(p.Call().Action(archive, "items/7").Header("X-Reason", "old").Bind(&out))
+	assert.Equal("7 archived", out)
+	assert.NoError(p.Call().Options("items/7").Bind(nil))
+}
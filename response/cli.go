@@ -1,26 +1,28 @@
 package response
 
 import (
-	"encoding/json"
+	"io"
 	"os"
-
-	"github.com/torniker/wrap/logger"
 )
 
 // NewCLI create an instance of cli responder
 func NewCLI() *CLI {
 	return &CLI{
+		body:    &counter{w: os.Stdout},
 		headers: make(map[string]string),
+		encoder: JSONEncoder{},
 	}
 }
 
 // CLI is type for cli responses
 type CLI struct {
 	// writer    http.ResponseWriter
+	body      *counter
 	headers   map[string]string
 	status    int
 	committed bool
 	output    interface{}
+	encoder   Encoder
 }
 
 // SetStatus sets response status code
@@ -50,8 +52,48 @@ func (c *CLI) Write(body interface{}) error {
 	}
 	c.committed = true
 	c.output = body
-	logger.Infof("<--- status: %v, body: %#v", c.status, body)
-	return json.NewEncoder(os.Stdout).Encode(body)
+	return c.encoder.Encode(c.body, body)
+}
+
+// SetOutput replaces stdout with w, e.g. a file given by the output flag
+func (c *CLI) SetOutput(w io.Writer) {
+	c.body = &counter{w: w}
+}
+
+// Stream commits and copies r to the output
+func (c *CLI) Stream(r io.Reader) error {
+	_, err := io.Copy(c.Writer(), r)
+	return err
+}
+
+// Writer commits and returns the output writer for chunked writes
+func (c *CLI) Writer() io.Writer {
+	if c.status == 0 {
+		c.SetStatus(200)
+	}
+	c.committed = true
+	return c.body
+}
+
+// Flush flushes buffered output, files and pipes are not buffered and are left as they are
+func (c *CLI) Flush() error {
+	if f, ok := c.body.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Finish sets status 200 if the handler did not respond, as HTTP server does
+func (c *CLI) Finish() error {
+	if c.status == 0 {
+		c.SetStatus(200)
+	}
+	return nil
+}
+
+// Size returns number of bytes written to the output
+func (c *CLI) Size() int {
+	return c.body.n
 }
 
 // SuccessWithNoContent commits and sets http status to 204 No Content
@@ -60,6 +102,11 @@ func (c *CLI) SuccessWithNoContent() {
 	c.committed = true
 }
 
+// SetEncoder sets encoder of the response body
+func (c *CLI) SetEncoder(e Encoder) {
+	c.encoder = e
+}
+
 // Output returns response output
 func (c *CLI) Output() interface{} {
 	return c.output
@@ -0,0 +1,205 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/shamaton/msgpack/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Encoder serializes response body
+type Encoder interface {
+	Encode(w io.Writer, v interface{}) error
+}
+
+// JSONEncoder encodes body as JSON
+type JSONEncoder struct{}
+
+// Encode writes v as JSON followed by newline
+func (JSONEncoder) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// XMLEncoder encodes body as XML with response root element
+type XMLEncoder struct{}
+
+// Encode writes v as XML
+func (XMLEncoder) Encode(w io.Writer, v interface{}) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "response"}})
+}
+
+// YAMLEncoder encodes body as YAML
+type YAMLEncoder struct{}
+
+// Encode writes v as YAML
+func (YAMLEncoder) Encode(w io.Writer, v interface{}) error {
+	enc := yaml.NewEncoder(w)
+	err := enc.Encode(v)
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// MsgpackEncoder encodes body as MessagePack
+type MsgpackEncoder struct{}
+
+// Encode writes v as MessagePack
+func (MsgpackEncoder) Encode(w io.Writer, v interface{}) error {
+	b, err := msgpack.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// TextEncoder writes body as plain text
+type TextEncoder struct{}
+
+// Encode writes strings, errors and Stringers as they are and other values in the manner of fmt.Print
+func (TextEncoder) Encode(w io.Writer, v interface{}) error {
+	var s string
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		s = val
+	case []byte:
+		s = string(val)
+	case error:
+		s = val.Error()
+	case fmt.Stringer:
+		s = val.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	_, err := io.WriteString(w, s+"\n")
+	return err
+}
+
+// CSVEncoder encodes slices as CSV rows.
+// Slices of structs and maps get header row with JSON field names and map keys,
+// slices of slices are written as they are and a single struct is written as one record.
+// Maps must have string keys, other maps are reported as unsupported.
+type CSVEncoder struct{}
+
+// Encode writes v as CSV
+func (CSVEncoder) Encode(w io.Writer, v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() == reflect.Struct || rv.Kind() == reflect.Map {
+		rv = reflect.ValueOf([]interface{}{rv.Interface()})
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return fmt.Errorf("csv: unsupported type %T", v)
+	}
+	cw := csv.NewWriter(w)
+	var header []string
+	for i := 0; i < rv.Len(); i++ {
+		item := indirect(rv.Index(i))
+		switch item.Kind() {
+		case reflect.Struct:
+			if header == nil {
+				header = structHeader(item.Type())
+				cw.Write(header)
+			}
+			cw.Write(structRecord(item))
+		case reflect.Map:
+			keyType := item.Type().Key()
+			if keyType.Kind() != reflect.String {
+				return fmt.Errorf("csv: unsupported map key type %v", keyType)
+			}
+			if header == nil {
+				header = mapHeader(item)
+				cw.Write(header)
+			}
+			record := make([]string, len(header))
+			for j, key := range header {
+				record[j] = cell(item.MapIndex(reflect.ValueOf(key).Convert(keyType)))
+			}
+			cw.Write(record)
+		case reflect.Slice, reflect.Array:
+			record := make([]string, item.Len())
+			for j := range record {
+				record[j] = cell(item.Index(j))
+			}
+			cw.Write(record)
+		default:
+			cw.Write([]string{cell(item)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func structHeader(t reflect.Type) []string {
+	var header []string
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := csvName(t.Field(i)); ok {
+			header = append(header, name)
+		}
+	}
+	return header
+}
+
+func structRecord(v reflect.Value) []string {
+	var record []string
+	for i := 0; i < v.NumField(); i++ {
+		if _, ok := csvName(v.Type().Field(i)); ok {
+			record = append(record, cell(v.Field(i)))
+		}
+	}
+	return record
+}
+
+func csvName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+func mapHeader(v reflect.Value) []string {
+	header := make([]string, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		header = append(header, key.String())
+	}
+	sort.Strings(header)
+	return header
+}
+
+func cell(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() || ((v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil()) {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -1,27 +1,31 @@
 package response
 
 import (
-	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
-
-	"github.com/torniker/wrap/logger"
+	"time"
 )
 
 // NewHTTP create an instance of HTTP responder
 func NewHTTP(w http.ResponseWriter) *HTTP {
 	return &HTTP{
 		writer:  w,
+		body:    &counter{w: w},
 		headers: make(map[string]string),
+		encoder: JSONEncoder{},
 	}
 }
 
 // HTTP is type for HTTP responses
 type HTTP struct {
 	writer    http.ResponseWriter
+	body      *counter
 	headers   map[string]string
 	status    int
 	committed bool
 	output    interface{}
+	encoder   Encoder
 }
 
 // SetStatus sets response status code
@@ -33,7 +37,7 @@ func (h *HTTP) SetStatus(status int) {
 // SetHeader sets header for responder
 func (h *HTTP) SetHeader(key, val string) {
 	h.headers[key] = val
-	h.writer.Header().Add(key, val)
+	h.writer.Header().Set(key, val)
 }
 
 // Status returns response status code
@@ -48,10 +52,74 @@ func (h *HTTP) Commited() bool {
 
 // Write commits and writes data into the response body
 func (h *HTTP) Write(body interface{}) error {
+	if h.status == 0 {
+		h.SetStatus(http.StatusOK)
+	}
 	h.committed = true
 	h.output = body
-	logger.Infof("<--- status: %v, body: %#v", h.status, body)
-	return json.NewEncoder(h.writer).Encode(body)
+	return h.encoder.Encode(h.body, body)
+}
+
+// Stream commits and copies r into the response body
+func (h *HTTP) Stream(r io.Reader) error {
+	_, err := io.Copy(h.Writer(), r)
+	return err
+}
+
+// Writer commits and returns writer of the response body for chunked writes
+func (h *HTTP) Writer() io.Writer {
+	if h.status == 0 {
+		h.SetStatus(http.StatusOK)
+	}
+	h.committed = true
+	return h.body
+}
+
+// Flush sends data written so far to the client
+func (h *HTTP) Flush() error {
+	return http.NewResponseController(h.writer).Flush()
+}
+
+// SetWriteDeadline overrides write timeout of the server for the response, zero time clears it.
+// Writers which do not support deadlines are left as they are.
+func (h *HTTP) SetWriteDeadline(t time.Time) error {
+	err := http.NewResponseController(h.writer).SetWriteDeadline(t)
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+// ServeContent commits and replies with content, it handles Range and conditional
+// requests of r using ETag and Last-Modified headers
+func (h *HTTP) ServeContent(r *http.Request, name string, modtime time.Time, content io.ReadSeeker) {
+	h.committed = true
+	http.ServeContent(contentWriter{h}, r, name, modtime, content)
+}
+
+// contentWriter records status and size of responses written by net/http
+type contentWriter struct {
+	h *HTTP
+}
+
+func (w contentWriter) Header() http.Header {
+	return w.h.writer.Header()
+}
+
+func (w contentWriter) WriteHeader(status int) {
+	w.h.SetStatus(status)
+}
+
+func (w contentWriter) Write(p []byte) (int, error) {
+	if w.h.status == 0 {
+		w.h.SetStatus(http.StatusOK)
+	}
+	return w.h.body.Write(p)
+}
+
+// Size returns number of body bytes written
+func (h *HTTP) Size() int {
+	return h.body.n
 }
 
 // SuccessWithNoContent commits and sets http status to 204 No Content
@@ -60,6 +128,11 @@ func (h *HTTP) SuccessWithNoContent() {
 	h.committed = true
 }
 
+// SetEncoder sets encoder of the response body
+func (h *HTTP) SetEncoder(e Encoder) {
+	h.encoder = e
+}
+
 // Output returns response output
 func (h *HTTP) Output() interface{} {
 	return h.output
@@ -1,13 +1,15 @@
 package response
 
 import (
-	"github.com/torniker/wrap/logger"
+	"bytes"
+	"io"
 )
 
 // NewResponse create an instance of sub responder
 func NewResponse() *Res {
 	return &Res{
 		headers: make(map[string]string),
+		encoder: JSONEncoder{},
 	}
 }
 
@@ -17,6 +19,8 @@ type Res struct {
 	status    int
 	committed bool
 	output    interface{}
+	encoder   Encoder
+	stream    *bytes.Buffer
 }
 
 // SetStatus sets response status code
@@ -46,7 +50,6 @@ func (r *Res) Write(body interface{}) error {
 	}
 	r.committed = true
 	r.output = body
-	logger.Infof("<--- status: %v, body: %#v", r.status, body)
 	return nil
 	// return json.NewEncoder(os.Stdout).Encode(body)
 }
@@ -57,11 +60,50 @@ func (r *Res) SuccessWithNoContent() {
 	r.committed = true
 }
 
-// Output returns response output
+// SetEncoder sets encoder of the response body, sub responses keep the body as it is
+func (r *Res) SetEncoder(e Encoder) {
+	r.encoder = e
+}
+
+// Stream commits and captures r in memory, the captured bytes are returned by Output
+func (r *Res) Stream(src io.Reader) error {
+	_, err := io.Copy(r.Writer(), src)
+	return err
+}
+
+// Writer commits and returns in memory buffer for chunked writes
+func (r *Res) Writer() io.Writer {
+	if r.status == 0 {
+		r.SetStatus(200)
+	}
+	r.committed = true
+	if r.stream == nil {
+		r.stream = new(bytes.Buffer)
+	}
+	return r.stream
+}
+
+// Flush does nothing since sub responses are kept in memory
+func (r *Res) Flush() error {
+	return nil
+}
+
+// Output returns response output, streamed responses return captured bytes
 func (r *Res) Output() interface{} {
+	if r.stream != nil {
+		return r.stream.Bytes()
+	}
 	return r.output
 }
 
+// Size returns number of streamed bytes since other sub responses are not serialized
+func (r *Res) Size() int {
+	if r.stream != nil {
+		return r.stream.Len()
+	}
+	return 0
+}
+
 // EnableCORS sets corresponsing headers to enable CORS
 func (r *Res) EnableCORS(origin, methods, headers string) {
 	r.SetHeader("Access-Control-Allow-Origin", origin)
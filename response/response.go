@@ -1,11 +1,26 @@
 package response
 
+import "io"
+
 // list of response types
 const (
 	TypeHTTP int = 1 + iota
 	TypeCLI
+	TypeWS
 )
 
+// counter counts bytes written to the underlying writer
+type counter struct {
+	w io.Writer
+	n int
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
 // Response defines methods for response object
 type Response interface {
 	SetStatus(status int)
@@ -13,7 +28,12 @@ type Response interface {
 	SetHeader(key, val string)
 	Commited() bool
 	Write(interface{}) error
+	Stream(r io.Reader) error
+	Writer() io.Writer
+	Flush() error
 	SuccessWithNoContent()
 	Output() interface{}
+	Size() int
+	SetEncoder(e Encoder)
 	EnableCORS(origin, methods, headers string)
 }
@@ -0,0 +1,170 @@
+package response
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// WSReply is frame sent over websocket, replies carry correlation id
+// of the request and pushed events carry event name instead
+type WSReply struct {
+	ID      string            `json:"id,omitempty"`
+	Event   string            `json:"event,omitempty"`
+	Status  int               `json:"status,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty"`
+}
+
+// NewWS create an instance of websocket responder, send writes frame to the connection
+func NewWS(id string, send func(frame interface{}) (int, error)) *WS {
+	return &WS{
+		id:      id,
+		send:    send,
+		headers: make(map[string]string),
+		encoder: JSONEncoder{},
+	}
+}
+
+// WS is type for websocket responses
+type WS struct {
+	id        string
+	send      func(frame interface{}) (int, error)
+	headers   map[string]string
+	status    int
+	committed bool
+	output    interface{}
+	encoder   Encoder
+	size      int
+	stream    *bytes.Buffer
+}
+
+// SetStatus sets response status code
+func (w *WS) SetStatus(status int) {
+	w.status = status
+}
+
+// SetHeader sets header for responder
+func (w *WS) SetHeader(key, val string) {
+	w.headers[key] = val
+}
+
+// Status returns response status code
+func (w *WS) Status() int {
+	return w.status
+}
+
+// Commited returns response status, streamed responses are committed
+func (w *WS) Commited() bool {
+	return w.committed || w.stream != nil
+}
+
+// Write commits and sends reply frame with the body
+func (w *WS) Write(body interface{}) error {
+	if w.status == 0 {
+		w.SetStatus(200)
+	}
+	body, err := w.encode(body)
+	if err != nil {
+		return err
+	}
+	w.output = body
+	return w.reply(body)
+}
+
+// encode returns body of the frame, other than JSON encoders are applied and the body is sent
+// as encoded text or as base64 for binary formats
+func (w *WS) encode(body interface{}) (interface{}, error) {
+	if _, ok := w.encoder.(JSONEncoder); ok || body == nil {
+		return body, nil
+	}
+	var b bytes.Buffer
+	err := w.encoder.Encode(&b, body)
+	if err != nil {
+		return nil, err
+	}
+	if utf8.Valid(b.Bytes()) {
+		return b.String(), nil
+	}
+	return b.Bytes(), nil
+}
+
+// SuccessWithNoContent commits and sends reply frame with status 204 No Content
+func (w *WS) SuccessWithNoContent() {
+	w.SetStatus(204)
+	w.reply(nil)
+}
+
+// Stream buffers r and sends it as the body of reply frame once the handler is done
+func (w *WS) Stream(r io.Reader) error {
+	_, err := io.Copy(w.Writer(), r)
+	return err
+}
+
+// Writer returns buffer which is sent as the body of reply frame once the handler is done
+func (w *WS) Writer() io.Writer {
+	if w.status == 0 {
+		w.SetStatus(200)
+	}
+	if w.stream == nil {
+		w.stream = new(bytes.Buffer)
+	}
+	return w.stream
+}
+
+// Flush does nothing since websocket reply is a single frame
+func (w *WS) Flush() error {
+	return nil
+}
+
+// Finish sends reply frame if the handler did not respond
+func (w *WS) Finish() error {
+	if w.committed {
+		return nil
+	}
+	if w.status == 0 {
+		w.SetStatus(200)
+	}
+	if w.stream != nil {
+		w.output = w.stream.String()
+		return w.reply(w.output)
+	}
+	return w.reply(nil)
+}
+
+func (w *WS) reply(body interface{}) error {
+	if w.committed {
+		return nil
+	}
+	w.committed = true
+	n, err := w.send(WSReply{
+		ID:      w.id,
+		Status:  w.status,
+		Headers: w.headers,
+		Body:    body,
+	})
+	w.size += n
+	return err
+}
+
+// Size returns number of bytes sent
+func (w *WS) Size() int {
+	return w.size
+}
+
+// SetEncoder sets encoder of the response body, frames are JSON and carry bodies of other encoders encoded
+func (w *WS) SetEncoder(e Encoder) {
+	w.encoder = e
+}
+
+// Output returns response output
+func (w *WS) Output() interface{} {
+	return w.output
+}
+
+// EnableCORS sets corresponsing headers to enable CORS
+func (w *WS) EnableCORS(origin, methods, headers string) {
+	w.SetHeader("Access-Control-Allow-Origin", origin)
+	w.SetHeader("Access-Control-Allow-Methods", methods)
+	w.SetHeader("Access-Control-Allow-Headers", headers)
+}
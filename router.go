@@ -0,0 +1,317 @@
+package wrap
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/torniker/wrap/request"
+)
+
+// Router matches request paths against registered routes.
+// Routes are stored in a prefix tree keyed by path segment, so lookup cost
+// depends on the path length and not on the number of registered routes.
+//
+// Pattern segments can be static (users), named params (:id) or
+// a trailing wildcard (*rest) which captures the remainder of the path.
+type Router struct {
+	root *node
+}
+
+type node struct {
+	static   map[string]*node
+	param    *node
+	wildcard *node
+	name     string
+	handlers map[request.Action]HandlerFunc
+	any      HandlerFunc
+	// description is shown by Routes, e.g. in CLI help
+	description string
+}
+
+// RouteInfo describes registered route, Actions is empty if the handler is called for any action
+type RouteInfo struct {
+	Pattern     string
+	Actions     []request.Action
+	Description string
+}
+
+// NewRouter returns empty router
+func NewRouter() *Router {
+	return &Router{
+		root: &node{},
+	}
+}
+
+// Route registers handler for the pattern which is called for any action
+func (r *Router) Route(pattern string, h HandlerFunc) {
+	n := r.insert(pattern)
+	if n.any != nil {
+		panic(fmt.Sprintf("wrap: route %q is already registered", pattern))
+	}
+	n.any = h
+}
+
+// Handle registers handler for the pattern and action
+func (r *Router) Handle(a request.Action, pattern string, h HandlerFunc) {
+	n := r.insert(pattern)
+	if n.handlers == nil {
+		n.handlers = make(map[request.Action]HandlerFunc)
+	}
+	if _, ok := n.handlers[a]; ok {
+		panic(fmt.Sprintf("wrap: route %v %q is already registered", a.String(), pattern))
+	}
+	n.handlers[a] = h
+}
+
+// Describe sets description of the pattern
+func (r *Router) Describe(pattern, description string) {
+	r.insert(pattern).description = description
+}
+
+// Routes returns registered routes sorted by pattern
+func (r *Router) Routes() []RouteInfo {
+	var routes []RouteInfo
+	r.root.walk(nil, &routes)
+	return routes
+}
+
+func (n *node) walk(segments []string, routes *[]RouteInfo) {
+	if n.any != nil || len(n.handlers) > 0 {
+		route := RouteInfo{
+			Pattern:     strings.Join(segments, "/"),
+			Description: n.description,
+		}
+		if n.any == nil {
+			for _, a := range request.Actions() {
+				if _, ok := n.handlers[a]; ok {
+					route.Actions = append(route.Actions, a)
+				}
+			}
+		}
+		*routes = append(*routes, route)
+	}
+	keys := make([]string, 0, len(n.static))
+	for key := range n.static {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		n.static[key].walk(append(segments[:len(segments):len(segments)], key), routes)
+	}
+	if n.param != nil {
+		n.param.walk(append(segments[:len(segments):len(segments)], ":"+n.param.name), routes)
+	}
+	if n.wildcard != nil {
+		n.wildcard.walk(append(segments[:len(segments):len(segments)], "*"+n.wildcard.name), routes)
+	}
+}
+
+// Serve finds the route matching the request, stores path params in ctx and calls the handler
+func (r *Router) Serve(ctx *Ctx) error {
+	params := make(map[string]string)
+	n := r.root.match(routeSegments(ctx.Request.Path().URL()), params)
+	if n == nil {
+		return ctx.NotFound()
+	}
+	a := ctx.Request.Action()
+	h, ok := n.handlers[a]
+	if !ok && a == request.HEAD {
+		h, ok = n.handlers[request.GET]
+	}
+	if !ok {
+		h = n.any
+	}
+	ctx.Request.SetParams(params)
+	if h == nil {
+		ctx.Response.SetHeader("Allow", n.allow())
+		if a == request.OPTIONS {
+			ctx.NoContent()
+			return nil
+		}
+		return ctx.MethodNotAllowed()
+	}
+	return h(ctx)
+}
+
+// allowOrder lists actions in the order they appear in Allow header, custom actions follow
+var allowOrder = []request.Action{
+	request.GET,
+	request.HEAD,
+	request.POST,
+	request.PUT,
+	request.PATCH,
+	request.DELETE,
+	request.OPTIONS,
+}
+
+// allow returns value of Allow header for the node, HEAD is allowed with GET and OPTIONS always
+func (n *node) allow() string {
+	allowed := map[request.Action]bool{request.OPTIONS: true}
+	for a := range n.handlers {
+		allowed[a] = true
+	}
+	if allowed[request.GET] {
+		allowed[request.HEAD] = true
+	}
+	var methods []string
+	for _, a := range allowOrder {
+		if allowed[a] {
+			methods = append(methods, method(a))
+			delete(allowed, a)
+		}
+	}
+	var custom []string
+	for a := range allowed {
+		custom = append(custom, method(a))
+	}
+	sort.Strings(custom)
+	return strings.Join(append(methods, custom...), ", ")
+}
+
+// lookup returns node matching the path, nil if there is none
+func (r *Router) lookup(u *url.URL) *node {
+	return r.root.match(routeSegments(u), make(map[string]string))
+}
+
+func (r *Router) insert(pattern string) *node {
+	n := r.root
+	segments := splitPath(pattern)
+	for i, seg := range segments {
+		switch {
+		case strings.HasPrefix(seg, ":"):
+			name := seg[1:]
+			if n.param == nil {
+				n.param = &node{name: name}
+			} else if n.param.name != name {
+				panic(fmt.Sprintf("wrap: param %q in %q conflicts with existing param %q", name, pattern, n.param.name))
+			}
+			n = n.param
+		case strings.HasPrefix(seg, "*"):
+			if i != len(segments)-1 {
+				panic(fmt.Sprintf("wrap: wildcard must be the last segment in %q", pattern))
+			}
+			name := seg[1:]
+			if n.wildcard == nil {
+				n.wildcard = &node{name: name}
+			} else if n.wildcard.name != name {
+				panic(fmt.Sprintf("wrap: wildcard %q in %q conflicts with existing wildcard %q", name, pattern, n.wildcard.name))
+			}
+			n = n.wildcard
+		default:
+			if n.static == nil {
+				n.static = make(map[string]*node)
+			}
+			child, ok := n.static[seg]
+			if !ok {
+				child = &node{}
+				n.static[seg] = child
+			}
+			n = child
+		}
+	}
+	return n
+}
+
+// match walks the tree preferring static segments over params and params over wildcards
+func (n *node) match(segments []string, params map[string]string) *node {
+	if len(segments) == 0 {
+		if n.any != nil || len(n.handlers) > 0 {
+			return n
+		}
+		if n.wildcard != nil {
+			params[n.wildcard.name] = ""
+			return n.wildcard
+		}
+		return nil
+	}
+	seg := segments[0]
+	if child, ok := n.static[seg]; ok {
+		if found := child.match(segments[1:], params); found != nil {
+			return found
+		}
+	}
+	if n.param != nil && seg != "" {
+		if found := n.param.match(segments[1:], params); found != nil {
+			params[n.param.name] = seg
+			return found
+		}
+	}
+	if n.wildcard != nil {
+		params[n.wildcard.name] = strings.Join(segments, "/")
+		return n.wildcard
+	}
+	return nil
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// routeSegments splits escaped path of the url so that escaped slashes stay within their segment
+func routeSegments(u *url.URL) []string {
+	segments := splitPath(u.EscapedPath())
+	for i, seg := range segments {
+		if s, err := url.PathUnescape(seg); err == nil {
+			segments[i] = s
+		}
+	}
+	return segments
+}
+
+// Route registers handler for the pattern which is called for any action
+func (p *Prog) Route(pattern string, h HandlerFunc, m ...Middleware) {
+	p.Router.Route(pattern, chain(h, m))
+}
+
+// Describe sets description of the route listed by CLI help
+func (p *Prog) Describe(pattern, description string) {
+	p.Router.Describe(pattern, description)
+}
+
+// Post registers handler for the pattern and POST action
+func (p *Prog) Post(pattern string, h HandlerFunc, m ...Middleware) {
+	p.Router.Handle(request.POST, pattern, chain(h, m))
+}
+
+// Get registers handler for the pattern and GET action
+func (p *Prog) Get(pattern string, h HandlerFunc, m ...Middleware) {
+	p.Router.Handle(request.GET, pattern, chain(h, m))
+}
+
+// Put registers handler for the pattern and PUT action
+func (p *Prog) Put(pattern string, h HandlerFunc, m ...Middleware) {
+	p.Router.Handle(request.PUT, pattern, chain(h, m))
+}
+
+// Patch registers handler for the pattern and PATCH action
+func (p *Prog) Patch(pattern string, h HandlerFunc, m ...Middleware) {
+	p.Router.Handle(request.PATCH, pattern, chain(h, m))
+}
+
+// Head registers handler for the pattern and HEAD action, GET handlers serve HEAD requests
+// of the pattern if there is none
+func (p *Prog) Head(pattern string, h HandlerFunc, m ...Middleware) {
+	p.Router.Handle(request.HEAD, pattern, chain(h, m))
+}
+
+// Options registers handler for the pattern and OPTIONS action, without it OPTIONS requests
+// respond with Allow header listing registered actions
+func (p *Prog) Options(pattern string, h HandlerFunc, m ...Middleware) {
+	p.Router.Handle(request.OPTIONS, pattern, chain(h, m))
+}
+
+// Delete registers handler for the pattern and DELETE action
+func (p *Prog) Delete(pattern string, h HandlerFunc, m ...Middleware) {
+	p.Router.Handle(request.DELETE, pattern, chain(h, m))
+}
+
+// Handle registers handler for the pattern and action, it is used for custom actions
+func (p *Prog) Handle(a request.Action, pattern string, h HandlerFunc, m ...Middleware) {
+	p.Router.Handle(a, pattern, chain(h, m))
+}
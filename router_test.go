@@ -0,0 +1,130 @@
+package wrap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+	"github.com/torniker/wrap/response"
+)
+
+func serve(p *Prog, a request.Action, path string) *Ctx {
+	u, _ := url.Parse(path)
+	ctx := p.NewCtx(request.NewRequest(a, u, nil), response.NewResponse())
+	err := p.handle(ctx)
+	if err != nil {
+		ctx.Error(err)
+	}
+	return ctx
+}
+
+func TestRouterParams(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("users/:id/posts/:post", func(c *Ctx) error {
+		return c.JSON(c.Param("id") + "-" + c.Param("post"))
+	})
+	p.Get("files/*path", func(c *Ctx) error {
+		return c.JSON(c.Param("path"))
+	})
+	ctx := serve(p, request.GET, "/users/42/posts/7")
+	assert.Equal(http.StatusOK, ctx.Response.Status())
+	assert.Equal(ResJSON{Data: "42-7"}, ctx.Response.Output())
+	ctx = serve(p, request.GET, "files/a/b/c.txt")
+	assert.Equal(ResJSON{Data: "a/b/c.txt"}, ctx.Response.Output())
+}
+
+func TestRouterPriority(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("users/me", func(c *Ctx) error {
+		return c.JSON("me")
+	})
+	p.Get("users/:id", func(c *Ctx) error {
+		return c.JSON(c.Param("id"))
+	})
+	p.Route("*rest", func(c *Ctx) error {
+		return c.JSON("fallback")
+	})
+	assert.Equal(ResJSON{Data: "me"}, serve(p, request.GET, "/users/me").Response.Output())
+	assert.Equal(ResJSON{Data: "5"}, serve(p, request.GET, "/users/5").Response.Output())
+	assert.Equal(ResJSON{Data: "fallback"}, serve(p, request.GET, "/users/5/extra").Response.Output())
+}
+
+func TestRouterNotFoundAndMethodNotAllowed(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Post("users", func(c *Ctx) error {
+		return c.JSON("created")
+	})
+	assert.Equal(http.StatusNotFound, serve(p, request.GET, "/posts").Response.Status())
+	assert.Equal(http.StatusMethodNotAllowed, serve(p, request.GET, "/users").Response.Status())
+	assert.Equal(http.StatusOK, serve(p, request.POST, "/users").Response.Status())
+}
+
+func TestRouterConflicts(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	h := func(c *Ctx) error { return nil }
+	p.Get("users/:id", h)
+	assert.Panics(func() { p.Get("users/:id", h) })
+	assert.Panics(func() { p.Get("users/:name/posts", h) })
+	assert.Panics(func() { p.Get("files/*path/more", h) })
+}
+
+func TestRouterMethods(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	h := func(c *Ctx) error {
+		return c.JSON(c.Request.Action().String())
+	}
+	p.Get("users/:id", h)
+	p.Patch("users/:id", h)
+	p.Delete("users/:id", h)
+	p.Handle(request.RegisterAction("archive"), "users/:id", h)
+
+	r := httptest.NewRequest(http.MethodPatch, "/users/1", nil)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.JSONEq(`{"data":"PATCH"}`, w.Body.String())
+
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	res, err := http.Head(srv.URL + "/users/1")
+	if assert.NoError(err) {
+		assert.Equal(http.StatusOK, res.StatusCode)
+		assert.Equal("application/json", res.Header.Get("Content-Type"))
+		res.Body.Close()
+	}
+
+	r = httptest.NewRequest(http.MethodOptions, "/users/1", nil)
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal("GET, HEAD, PATCH, DELETE, OPTIONS, ARCHIVE", w.Header().Get("Allow"))
+
+	r = httptest.NewRequest("ARCHIVE", "/users/1", nil)
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{"data":"ARCHIVE"}`, w.Body.String())
+
+	r = httptest.NewRequest("UNKNOWN", "/users/1", nil)
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.Equal(http.StatusMethodNotAllowed, w.Code)
+
+	r = httptest.NewRequest(http.MethodPut, "/users/1", nil)
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.Equal(http.StatusMethodNotAllowed, w.Code)
+	assert.Equal("GET, HEAD, PATCH, DELETE, OPTIONS, ARCHIVE", w.Header().Get("Allow"))
+
+	for _, name := range []string{"get", "HEAD", "options", "Patch"} {
+		a := request.NewActionFromString(name)
+		assert.True(a.IsValid(), name)
+	}
+}
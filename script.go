@@ -0,0 +1,159 @@
+package wrap
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ScriptOptions configures RunScript
+type ScriptOptions struct {
+	// StopOnError stops the script at the first failed line
+	StopOnError bool
+	// Vars are variables set before the first line
+	Vars map[string]string
+	// Out receives output of the commands, it is stdout if nil
+	Out io.Writer
+}
+
+// ScriptError describes failed line of the script
+type ScriptError struct {
+	Line    int
+	Command string
+	Err     error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("line %v: %v: %v", e.Line, e.Command, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// RunScriptFile runs commands of the file with RunScript
+func (p *Prog) RunScriptFile(path string, opts ScriptOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return p.RunScript(f, opts)
+}
+
+// RunScript runs CLI commands one per line through DefaultHandler. Lines starting with # are comments,
+// expect checks status of the previous command and set captures values of the responses, e.g.
+//
+//	post users {"name": "ann"}
+//	expect 201
+//	set user=$last.data.id
+//	get users/$user
+//
+// A line fails if it is invalid, its expect does not match or its command responds with non 2xx status
+// and no expect follows. It returns errors of the failed lines joined. Start hooks run before
+// the first line and the app is shut down after the last one.
+func (p *Prog) RunScript(r io.Reader, opts ScriptOptions) (err error) {
+	err = p.start(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), p.DrainTimeout)
+		defer cancel()
+		err = errors.Join(err, p.Shutdown(ctx))
+	}()
+	return p.runScript(r, opts)
+}
+
+// runScript runs lines of the script, see RunScript
+func (p *Prog) runScript(r io.Reader, opts ScriptOptions) error {
+	out := opts.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	s := p.newSession(out)
+	for name, val := range opts.Vars {
+		s.vars[name] = val
+	}
+	lr := &lineReader{Reader: bufio.NewReader(r)}
+	s.stdin = func() io.Reader {
+		r, _ := readUntilDot(lr)
+		return r
+	}
+	var errs []error
+	// pending is failure of the last command which is reported unless expect follows
+	var pending *ScriptError
+	fail := func(e *ScriptError) bool {
+		errs = append(errs, e)
+		return opts.StopOnError
+	}
+	var command string
+	var start int
+	for {
+		line, readErr := lr.ReadString('\n')
+		if line == "" && readErr != nil {
+			break
+		}
+		if command == "" {
+			start = lr.n
+		}
+		command += line
+		trimmed := strings.TrimSpace(command)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			command = ""
+			continue
+		}
+		if strings.ToLower(trimmed) == "exit" {
+			break
+		}
+		expect := strings.ToLower(strings.Fields(trimmed)[0]) == "expect"
+		if pending != nil && !expect {
+			if fail(pending) {
+				return errors.Join(errs...)
+			}
+			pending = nil
+		}
+		commands := s.commands
+		err := s.exec(command)
+		if err == ErrIncomplete && readErr == nil {
+			continue
+		}
+		command = ""
+		if err != nil {
+			pending = nil
+			if fail(&ScriptError{Line: start, Command: trimmed, Err: err}) {
+				return errors.Join(errs...)
+			}
+			continue
+		}
+		if expect {
+			pending = nil
+		}
+		if status, ok := s.vars["status"].(int); ok && s.commands > commands && (status < 200 || status >= 300) {
+			pending = &ScriptError{Line: start, Command: trimmed, Err: fmt.Errorf("unexpected status %v", status)}
+		}
+	}
+	if pending != nil {
+		errs = append(errs, pending)
+	}
+	return errors.Join(errs...)
+}
+
+// lineReader counts lines read
+type lineReader struct {
+	*bufio.Reader
+	n int
+}
+
+// ReadString reads up to the delimiter and counts the line
+func (r *lineReader) ReadString(delim byte) (string, error) {
+	s, err := r.Reader.ReadString(delim)
+	if s != "" {
+		r.n++
+	}
+	return s, err
+}
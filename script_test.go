@@ -0,0 +1,88 @@
+package wrap
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunScript(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	users := map[string]string{}
+	p.Post("users", func(c *Ctx) error {
+		var in struct {
+			Name string `json:"name"`
+		}
+		err := c.Bind(&in)
+		if err != nil {
+			return err
+		}
+		if in.Name == "" {
+			return c.BadRequest("name is required")
+		}
+		id := c.Flag("prefix") + "1"
+		users[id] = in.Name
+		c.Response.SetStatus(201)
+		return c.JSON(map[string]string{"id": id})
+	})
+	p.Get("users/:id", func(c *Ctx) error {
+		name, ok := users[c.Param("id")]
+		if !ok {
+			return c.NotFound()
+		}
+		return c.JSON(map[string]string{"name": name})
+	})
+	script := `# seed users
+post users --prefix=$prefix {
+  "name": "ann"
+}
+expect 201
+set user=$last.data
+get users/$user.id
+expect 2xx
+set name=$last.data.name
+
+post users {}
+expect 400 422
+get users/missing
+get users/$nobody
+post users {}
+expect 201
+`
+	err := p.RunScript(strings.NewReader(script), ScriptOptions{Vars: map[string]string{"prefix": "u"}, Out: io.Discard})
+	assert.EqualError(err, "line 13: get users/missing: unexpected status 404\n"+
+		"line 14: get users/$nobody: unknown variable $nobody\n"+
+		"line 16: expect 201: expected status 201, got 400")
+	assert.Equal(map[string]string{"u1": "ann"}, users)
+
+	err = p.RunScript(strings.NewReader(script), ScriptOptions{StopOnError: true, Out: io.Discard})
+	assert.EqualError(err, "line 2: post users --prefix=$prefix {\n  \"name\": \"ann\"\n}: unknown variable $prefix")
+	var serr *ScriptError
+	assert.ErrorAs(err, &serr)
+	assert.Equal(2, serr.Line)
+}
+
+func TestRunScriptLifecycle(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var events []string
+	p.OnStart(func(ctx context.Context) error {
+		events = append(events, "start")
+		return nil
+	})
+	p.OnShutdown(func(ctx context.Context) error {
+		events = append(events, "shutdown")
+		return nil
+	})
+	p.Post("seed", func(c *Ctx) error {
+		events = append(events, "seed")
+		return nil
+	})
+	err := p.RunScript(strings.NewReader("post seed\n"), ScriptOptions{Out: io.Discard})
+	assert.NoError(err)
+	assert.Equal([]string{"start", "seed", "shutdown"}, events)
+}
@@ -0,0 +1,325 @@
+package wrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/torniker/wrap/request"
+)
+
+// MaxCapture is the size of output kept as $last variable, larger output is not captured
+const MaxCapture = 1 << 20
+
+// varPattern matches $name and ${name} variable at the start of string followed by optional .path
+var varPattern = regexp.MustCompile(`^\$(?:\{([A-Za-z_]\w*)((?:\.[\w-]+)*)\}|([A-Za-z_]\w*)((?:\.[\w-]+)*))`)
+
+var varName = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+
+// session runs CLI commands and keeps their variables, $last holds the body and $status the status
+// of the last response
+type session struct {
+	prog   *Prog
+	vars   map[string]interface{}
+	out    io.Writer
+	pretty bool
+	color  bool
+	// stdin returns body of commands with StdinBody
+	stdin func() io.Reader
+	// commands counts executed commands
+	commands int
+	// console of the commands, nil keeps outputs of the app
+	console *console
+}
+
+func (p *Prog) newSession(out io.Writer) *session {
+	return &session{
+		prog: p,
+		vars: make(map[string]interface{}),
+		out:  out,
+	}
+}
+
+// run reads commands until exit command, end of input or shutdown, incomplete commands continue on the next line
+func (s *session) run(readLine func() (string, error), prompt func(string)) error {
+	var command string
+	prompt("--> ")
+	for {
+		line, readErr := readLine()
+		if readErr != nil && line == "" {
+			return readErr
+		}
+		if s.prog.stopping() {
+			return nil
+		}
+		command += line
+		trimmed := strings.TrimSpace(command)
+		if strings.ToLower(trimmed) == "exit" {
+			return nil
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			command = ""
+			prompt("--> ")
+			continue
+		}
+		err := s.exec(command)
+		if err == ErrIncomplete && readErr == nil {
+			prompt("... ")
+			continue
+		}
+		command = ""
+		if err != nil {
+			fmt.Fprintln(s.out, err)
+		}
+		if readErr != nil {
+			return readErr
+		}
+		prompt("--> ")
+	}
+}
+
+// exec runs builtin or command with variables expanded
+func (s *session) exec(command string) error {
+	fields := append(strings.Fields(command), "")
+	switch strings.ToLower(fields[0]) {
+	case "help":
+		return s.help()
+	case "set":
+		return s.set(strings.TrimSpace(command[strings.Index(command, fields[0])+len(fields[0]):]))
+	case "unset":
+		for _, name := range fields[1 : len(fields)-1] {
+			delete(s.vars, name)
+		}
+		return nil
+	case "vars":
+		return s.set("")
+	case "expect":
+		return s.expect(fields[1 : len(fields)-1])
+	}
+	// the command is complete before expanding, so a variable error does not split it
+	if _, err := ParseCommand(command); err == ErrIncomplete {
+		return err
+	}
+	cmd, err := parseCommand(&scanner{s: command, vars: s.variable})
+	if err != nil {
+		return err
+	}
+	var stdin io.Reader
+	if cmd.Body == StdinBody && s.stdin != nil {
+		stdin = s.stdin()
+	}
+	capture := &capture{}
+	out := io.Writer(s.out)
+	var o *output
+	if s.pretty {
+		o = &output{w: s.out, color: s.color}
+		out = o
+	}
+	ctx, err := s.prog.runCommand(cmd, stdin, io.MultiWriter(out, capture), s.console)
+	if err != nil {
+		return err
+	}
+	if o != nil {
+		err = o.Close()
+		if err != nil {
+			return err
+		}
+	}
+	s.commands++
+	status := ctx.Response.Status()
+	s.vars["status"] = status
+	s.vars["last"] = capture.value()
+	if s.pretty && (status < 200 || status >= 300) {
+		fmt.Fprintln(s.out, s.paint(colorError, fmt.Sprintf("%v %v", status, http.StatusText(status))))
+	}
+	return nil
+}
+
+// variable returns value of variable reference at the start of ref and length of the reference,
+// the length is 0 if ref does not start with a variable
+func (s *session) variable(ref string) (interface{}, int, error) {
+	m := varPattern.FindStringSubmatchIndex(ref)
+	if m == nil {
+		return nil, 0, nil
+	}
+	v, err := s.lookup(ref, m)
+	return v, m[1], err
+}
+
+// lookup returns value of the variable matched by varPattern at m
+func (s *session) lookup(line string, m []int) (interface{}, error) {
+	if m[2] < 0 {
+		m = append(m[:2:2], m[6:]...)
+	}
+	name, path := line[m[2]:m[3]], line[m[4]:m[5]]
+	v, ok := s.vars[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable $%v", name)
+	}
+	v, err := lookupRef(v, strings.Split(strings.TrimPrefix(path, "."), "."))
+	if err != nil {
+		return nil, fmt.Errorf("invalid variable %v: %v", line[m[0]:m[1]], err)
+	}
+	return v, nil
+}
+
+// expect checks status of the last response, statuses are codes like 201 or classes like 4xx
+func (s *session) expect(statuses []string) error {
+	if len(statuses) == 0 {
+		return fmt.Errorf("missing status, expected e.g. expect 201 or expect 4xx")
+	}
+	status, ok := s.vars["status"].(int)
+	if !ok {
+		return fmt.Errorf("no response to check")
+	}
+	code := strconv.Itoa(status)
+	for _, expected := range statuses {
+		if len(expected) != 3 {
+			return fmt.Errorf("invalid status %q", expected)
+		}
+		if expected == code || (strings.HasSuffix(strings.ToLower(expected), "xx") && expected[0] == code[0]) {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected status %v, got %v", strings.Join(statuses, " or "), status)
+}
+
+// set stores variable given as name=value, without argument it lists variables
+func (s *session) set(arg string) error {
+	if arg == "" {
+		names := make([]string, 0, len(s.vars))
+		for name := range s.vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(s.out, "%v=%v\n", name, varString(s.vars[name]))
+		}
+		return nil
+	}
+	kv := strings.SplitN(arg, "=", 2)
+	name := strings.TrimSpace(kv[0])
+	if len(kv) != 2 || !varName.MatchString(name) {
+		return fmt.Errorf("invalid variable %q, expected set name=value", arg)
+	}
+	raw := strings.TrimSpace(kv[1])
+	if m := varPattern.FindStringSubmatchIndex(raw); m != nil && m[1] == len(raw) {
+		// single variable keeps the type of its value, e.g. set user=$last.data
+		v, err := s.lookup(raw, m)
+		if err != nil {
+			return err
+		}
+		s.vars[name] = v
+		return nil
+	}
+	sc := &scanner{s: raw, vars: s.variable}
+	var value string
+	var err error
+	if !sc.done() && (sc.peek() == '{' || sc.peek() == '[') {
+		value, err = sc.json()
+	} else {
+		value, err = sc.word()
+	}
+	if err != nil {
+		return err
+	}
+	if rest, _ := sc.word(); rest != "" {
+		return fmt.Errorf("unexpected argument %q, quote values with spaces", rest)
+	}
+	s.vars[name] = value
+	return nil
+}
+
+// help lists registered routes and builtin commands
+func (s *session) help() error {
+	w := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+	for _, route := range s.prog.Router.Routes() {
+		actions := "*"
+		if len(route.Actions) > 0 {
+			names := make([]string, len(route.Actions))
+			for i, a := range route.Actions {
+				names[i] = strings.ToLower(method(a))
+			}
+			actions = strings.Join(names, ",")
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\n", actions, route.Pattern, route.Description)
+	}
+	err := w.Flush()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.out)
+	fmt.Fprintln(w, "set name=value\tset variable used as $name, $last and $status hold the last response")
+	fmt.Fprintln(w, "unset name\tremove variable")
+	fmt.Fprintln(w, "vars\tlist variables")
+	fmt.Fprintln(w, "expect status\tcheck status of the last response, e.g. expect 201 or expect 4xx")
+	fmt.Fprintln(w, "exit\tquit")
+	return w.Flush()
+}
+
+func (s *session) paint(color, text string) string {
+	if !s.color {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// varString formats variable for the command, strings are inserted as they are and other values as JSON
+func varString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// capture keeps up to MaxCapture bytes of the output
+type capture struct {
+	bytes.Buffer
+	over bool
+}
+
+func (c *capture) Write(b []byte) (int, error) {
+	if c.over || c.Len()+len(b) > MaxCapture {
+		c.over = true
+		c.Reset()
+		return len(b), nil
+	}
+	return c.Buffer.Write(b)
+}
+
+// value returns captured JSON decoded, other output as string and nil if output was too large
+func (c *capture) value() interface{} {
+	if c.over {
+		return nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(c.Bytes()))
+	dec.UseNumber()
+	if dec.Decode(&v) == nil {
+		return v
+	}
+	return strings.TrimRight(c.String(), "\n")
+}
+
+// actionNames returns lower case names of actions used in commands
+func actionNames() []string {
+	var names []string
+	for _, a := range request.Actions() {
+		names = append(names, strings.ToLower(method(a)))
+	}
+	return names
+}
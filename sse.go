@@ -0,0 +1,190 @@
+package wrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/torniker/wrap/response"
+)
+
+// DefaultHeartbeat is the default interval of comments which keep event streams alive
+const DefaultHeartbeat = 15 * time.Second
+
+// LastEventIDHeader carries id of the last event received by the client before it reconnected
+const LastEventIDHeader = "Last-Event-ID"
+
+// LastEventIDFlag is the flag alternative of LastEventIDHeader, e.g. ?last-event-id=42
+const LastEventIDFlag = "last-event-id"
+
+// ErrInvalidEvent is returned by Send for events whose ID or Name contain line breaks,
+// which would let them inject fields into the stream
+var ErrInvalidEvent = errors.New("sse: event id and name must not contain line breaks")
+
+// Event is a server-sent event, strings are sent as they are and other data is encoded as JSON
+type Event struct {
+	ID    string
+	Name  string
+	Data  interface{}
+	Retry time.Duration
+}
+
+// EventStream sends events to the client, HTTP clients receive text/event-stream
+// and CLI users see every event printed as a line
+type EventStream struct {
+	ctx *Ctx
+	mu  sync.Mutex
+	w   io.Writer
+	sse bool
+}
+
+// SSE streams events sent by f, HTTP streams are kept alive with heartbeat comments
+// every Prog.Heartbeat until f returns
+//
+//	return ctx.SSE(func(s *wrap.EventStream) error {
+//		for p := range job.Progress(s.LastEventID()) {
+//			err := s.Send(wrap.Event{ID: p.ID, Name: "progress", Data: p})
+//			if err != nil {
+//				return err
+//			}
+//		}
+//		return nil
+//	})
+func (ctx *Ctx) SSE(f func(s *EventStream) error) error {
+	s := &EventStream{ctx: ctx}
+	if _, ok := ctx.Response.(*response.HTTP); ok {
+		s.sse = true
+		ctx.Response.SetHeader("Content-Type", "text/event-stream")
+		ctx.Response.SetHeader("Cache-Control", "no-cache")
+		ctx.Response.SetHeader("Connection", "keep-alive")
+		ctx.Response.SetHeader("X-Accel-Buffering", "no")
+	} else {
+		ctx.Response.SetHeader("Content-Type", "text/plain")
+	}
+	s.w = ctx.Response.Writer()
+	s.extend()
+	ctx.Response.Flush()
+	if s.sse && ctx.Prog.Heartbeat > 0 {
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go s.heartbeat(ctx.Context(), ctx.Prog.Heartbeat, stop, done)
+		defer func() {
+			close(stop)
+			<-done
+		}()
+	}
+	return f(s)
+}
+
+// LastEventID returns id of the last event the client received before reconnecting
+func (s *EventStream) LastEventID() string {
+	if id := s.ctx.Request.Header().Get(LastEventIDHeader); id != "" {
+		return id
+	}
+	return s.ctx.Flag(LastEventIDFlag)
+}
+
+// Send writes the event and flushes it to the client, it fails once the request context is done
+func (s *EventStream) Send(e Event) error {
+	err := s.ctx.Context().Err()
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(e.ID, "\r\n") || strings.ContainsAny(e.Name, "\r\n") {
+		return ErrInvalidEvent
+	}
+	data, err := eventData(e.Data)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	if s.sse {
+		if e.ID != "" {
+			fmt.Fprintf(&b, "id: %v\n", e.ID)
+		}
+		if e.Name != "" {
+			fmt.Fprintf(&b, "event: %v\n", e.Name)
+		}
+		if e.Retry > 0 {
+			fmt.Fprintf(&b, "retry: %v\n", e.Retry.Milliseconds())
+		}
+		for _, line := range strings.Split(data, "\n") {
+			fmt.Fprintf(&b, "data: %v\n", line)
+		}
+		b.WriteString("\n")
+	} else {
+		if e.Name != "" {
+			fmt.Fprintf(&b, "%v: ", e.Name)
+		}
+		b.WriteString(data)
+		b.WriteString("\n")
+	}
+	return s.write(b.String())
+}
+
+func (s *EventStream) write(str string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.extend()
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(s.w, str)
+	if err != nil {
+		return err
+	}
+	return s.ctx.Response.Flush()
+}
+
+// extend moves write deadline of HTTP streams by the server write timeout from now,
+// otherwise the timeout counted from the start of the request cuts the stream
+func (s *EventStream) extend() error {
+	res, ok := s.ctx.Response.(*response.HTTP)
+	if !ok {
+		return nil
+	}
+	var deadline time.Time
+	if timeout := s.ctx.Prog.Server.WriteTimeout; timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	return res.SetWriteDeadline(deadline)
+}
+
+func (s *EventStream) heartbeat(c context.Context, interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-c.Done():
+			return
+		case <-t.C:
+			if s.write(": heartbeat\n\n") != nil {
+				return
+			}
+		}
+	}
+}
+
+func eventData(v interface{}) (string, error) {
+	switch d := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return d, nil
+	case []byte:
+		return string(d), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
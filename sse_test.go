@@ -0,0 +1,137 @@
+package wrap
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+	"github.com/torniker/wrap/response"
+)
+
+type progress struct {
+	Done int `json:"done"`
+}
+
+func progressHandler(c *Ctx) error {
+	return c.SSE(func(s *EventStream) error {
+		err := s.Send(Event{ID: "1", Name: "progress", Data: progress{Done: 50}, Retry: time.Second})
+		if err != nil {
+			return err
+		}
+		time.Sleep(30 * time.Millisecond)
+		return s.Send(Event{ID: "2", Data: "resumed after " + s.LastEventID() + "\nbye"})
+	})
+}
+
+func TestSSE(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Heartbeat = 10 * time.Millisecond
+	p.Get("jobs/:id/events", progressHandler)
+	r := httptest.NewRequest(http.MethodGet, "/jobs/1/events", nil)
+	r.Header.Set(LastEventIDHeader, "7")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal("no-cache", w.Header().Get("Cache-Control"))
+	body := w.Body.String()
+	assert.Contains(body, "id: 1\nevent: progress\nretry: 1000\ndata: {\"done\":50}\n\n")
+	assert.Contains(body, ": heartbeat\n\n")
+	assert.Contains(body, "id: 2\ndata: resumed after 7\ndata: bye\n\n")
+}
+
+func TestSSECLI(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("jobs/:id/events", progressHandler)
+	var out bytes.Buffer
+	res := response.NewCLI()
+	res.SetOutput(&out)
+	u, _ := url.Parse("http://app.cli/jobs/1/events?last-event-id=3")
+	p.serve(p.NewCtx(request.NewCLI(request.GET, u, nil), res))
+	assert.Equal("progress: {\"done\":50}\nresumed after 3\nbye\n", out.String())
+}
+
+func TestSSECLIPipe(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("jobs/:id/events", progressHandler)
+	r, w, err := os.Pipe()
+	assert.NoError(err)
+	defer r.Close()
+	res := response.NewCLI()
+	res.SetOutput(w)
+	u, _ := url.Parse("http://app.cli/jobs/1/events")
+	ctx := p.NewCtx(request.NewCLI(request.GET, u, nil), res)
+	p.serve(ctx)
+	w.Close()
+	out, err := io.ReadAll(r)
+	assert.NoError(err)
+	assert.Equal(http.StatusOK, ctx.Response.Status())
+	assert.Equal("progress: {\"done\":50}\nresumed after \nbye\n", string(out))
+}
+
+func TestSSEWriteTimeout(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Heartbeat = 0
+	p.Server.WriteTimeout = 300 * time.Millisecond
+	p.Get("ticks", func(c *Ctx) error {
+		return c.SSE(func(s *EventStream) error {
+			for i := 0; i < 10; i++ {
+				time.Sleep(100 * time.Millisecond)
+				err := s.Send(Event{Data: i})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+	done := make(chan error)
+	go func() {
+		done <- p.Serve(l)
+	}()
+	res, err := http.Get("http://" + l.Addr().String() + "/ticks")
+	assert.NoError(err)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.NoError(err)
+	assert.Equal(10, strings.Count(string(body), "data: "))
+	assert.NoError(p.Shutdown(context.Background()))
+	assert.NoError(<-done)
+}
+
+func TestSSEInvalidEvent(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	var errs []error
+	p.Get("events", func(c *Ctx) error {
+		return c.SSE(func(s *EventStream) error {
+			errs = append(errs,
+				s.Send(Event{ID: "1\ndata: forged", Data: "a"}),
+				s.Send(Event{Name: "tick\r\nid: 9", Data: "b"}),
+				s.Send(Event{ID: "2", Name: "tick", Data: "c"}),
+			)
+			return nil
+		})
+	})
+	w := serveRequest(p, http.MethodGet, "/events", nil, nil)
+	assert.Equal([]error{ErrInvalidEvent, ErrInvalidEvent, nil}, errs)
+	assert.NotContains(w.Body.String(), "forged")
+	assert.NotContains(w.Body.String(), "id: 9")
+	assert.Contains(w.Body.String(), "id: 2\nevent: tick\ndata: c\n\n")
+}
@@ -0,0 +1,112 @@
+package wrap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/torniker/wrap/logger"
+	"github.com/torniker/wrap/request"
+	"github.com/torniker/wrap/response"
+)
+
+// StartStdio serves JSON lines protocol on stdin and stdout, see ServeStdio
+func (p *Prog) StartStdio() error {
+	err := p.start(context.Background())
+	if err != nil {
+		return err
+	}
+	return p.ServeStdio(os.Stdin, os.Stdout)
+}
+
+// ServeStdio reads request frames from in, one JSON object per line, and writes response frames to out
+// in the same way. Frames are the same as websocket ones:
+//
+//	{"id":"1","action":"get","path":"users/42","flags":{},"headers":{},"body":{}}
+//	{"id":"1","status":200,"headers":{},"body":{}}
+//
+// Requests are handled concurrently and replies carry id of the request. It returns when in ends
+// or the app shuts down, after replies of in-flight requests are written.
+// If out is os.Stdout, default loggers and access log written to stdout are moved to stderr so stdout
+// carries only the frames, custom loggers should not write to stdout either.
+func (p *Prog) ServeStdio(in io.Reader, out io.Writer) error {
+	s := &stdio{
+		prog: p,
+		out:  out,
+	}
+	if out == io.Writer(os.Stdout) {
+		s.console = p.newConsole(os.Stderr, nil)
+		logger.SetDefault(logger.Default().WithSinks(logger.NewSink(os.Stderr, logger.TextEncoder{})))
+	}
+	defer s.inflight.Wait()
+	r := bufio.NewReader(in)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 && !p.stopping() {
+			s.receive(line)
+		}
+		if err == io.EOF || p.stopping() {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// stdio writes frames of ServeStdio
+type stdio struct {
+	prog     *Prog
+	mu       sync.Mutex
+	out      io.Writer
+	inflight sync.WaitGroup
+	console  *console
+}
+
+func (s *stdio) receive(line []byte) {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	var m request.WSMessage
+	err := json.Unmarshal(line, &m)
+	if err != nil {
+		s.send(response.WSReply{
+			Status: http.StatusBadRequest,
+			Body:   ErrorBadRequest{Message: "invalid message"},
+		})
+		return
+	}
+	s.inflight.Add(1)
+	go func() {
+		defer s.inflight.Done()
+		s.dispatch(m)
+	}()
+}
+
+func (s *stdio) dispatch(m request.WSMessage) {
+	res := response.NewWS(m.ID, s.send)
+	action, u, ok := parseFrame(m, res)
+	if !ok {
+		return
+	}
+	ctx := s.prog.NewCtx(request.NewStdio(action, u, m), res)
+	ctx.console = s.console
+	ctx.context = s.prog.lifecycle.base
+	s.prog.serve(ctx)
+}
+
+// send writes frame as a JSON line and returns its size
+func (s *stdio) send(frame interface{}) (int, error) {
+	b, err := json.Marshal(frame)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.out.Write(append(b, '\n'))
+}
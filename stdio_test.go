@@ -0,0 +1,84 @@
+package wrap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/logger"
+)
+
+func TestServeStdio(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("users/:id", func(c *Ctx) error {
+		return c.JSON(map[string]string{"id": c.Param("id"), "fields": c.Flag("fields"), "token": c.Header("X-Token")})
+	})
+	in := `{"id":"1","action":"get","path":"users/42","flags":{"fields":["name"]},"headers":{"X-Token":["t"]}}
+not json
+
+{"id":"2","action":"jump","path":"users/42"}
+{"id":"3","action":"get","path":"missing"}
+`
+	var log bytes.Buffer
+	p.AccessLog = NewAccessLog(&log, JSONLogFormat)
+	var out bytes.Buffer
+	assert.NoError(p.ServeStdio(strings.NewReader(in), &out))
+	var frames []map[string]interface{}
+	sc := bufio.NewScanner(&out)
+	for sc.Scan() {
+		var f map[string]interface{}
+		assert.NoError(json.Unmarshal(sc.Bytes(), &f))
+		frames = append(frames, f)
+	}
+	sort.Slice(frames, func(i, j int) bool {
+		a, _ := frames[i]["id"].(string)
+		b, _ := frames[j]["id"].(string)
+		return a < b
+	})
+	assert.Len(frames, 4)
+	assert.Equal(float64(400), frames[0]["status"])
+	assert.Equal(map[string]interface{}{"data": map[string]interface{}{"id": "42", "fields": "name", "token": "t"}}, frames[1]["body"])
+	assert.Equal(float64(200), frames[1]["status"])
+	assert.Equal(float64(400), frames[2]["status"])
+	assert.Equal(float64(404), frames[3]["status"])
+
+	var entry map[string]interface{}
+	assert.NoError(json.NewDecoder(&log).Decode(&entry))
+	assert.Equal(TransportStdio, entry["transport"])
+}
+
+func TestServeStdioStdout(t *testing.T) {
+	assert := assert.New(t)
+	stdout, stderr, defaultLogger := os.Stdout, os.Stderr, logger.Default()
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		logger.SetDefault(defaultLogger)
+	}()
+	outR, outW, err := os.Pipe()
+	assert.NoError(err)
+	errR, errW, err := os.Pipe()
+	assert.NoError(err)
+	os.Stdout, os.Stderr = outW, errW
+
+	p := New()
+	p.AccessLog = NewAccessLog(os.Stdout, JSONLogFormat)
+	p.Get("hello", func(c *Ctx) error {
+		c.Logger().Info("hello")
+		return c.JSON("hi")
+	})
+	assert.NoError(p.ServeStdio(strings.NewReader(`{"id":"1","action":"get","path":"hello"}`+"\n"), os.Stdout))
+	outW.Close()
+	errW.Close()
+	out, _ := io.ReadAll(outR)
+	logs, _ := io.ReadAll(errR)
+	assert.JSONEq(`{"id":"1","status":200,"headers":{"Content-Type":"application/json"},"body":{"data":"hi"}}`, string(out))
+	assert.Contains(string(logs), "hello")
+	assert.Contains(string(logs), `"transport":"stdio"`)
+}
@@ -0,0 +1,87 @@
+package wrap
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/torniker/wrap/request"
+	"github.com/torniker/wrap/response"
+)
+
+// OutputFlag is the CLI flag which writes response to the file instead of stdout, e.g. ?output=report.csv
+const OutputFlag = "output"
+
+// contentServer is implemented by responses which handle Range and conditional requests
+type contentServer interface {
+	ServeContent(r *http.Request, name string, modtime time.Time, content io.ReadSeeker)
+}
+
+// Stream responds with content of r without buffering it
+func (ctx *Ctx) Stream(contentType string, r io.Reader) error {
+	ctx.Response.SetHeader("Content-Type", contentType)
+	return ctx.Response.Stream(r)
+}
+
+// File responds with the file inline, see Attachment
+func (ctx *Ctx) File(path string) error {
+	return ctx.serveFile(path, "inline", filepath.Base(path))
+}
+
+// Attachment responds with the file as download named name.
+// HTTP responses support Range requests and are validated with ETag and Last-Modified headers,
+// other transports stream the whole file.
+func (ctx *Ctx) Attachment(path, name string) error {
+	return ctx.serveFile(path, "attachment", name)
+}
+
+func (ctx *Ctx) serveFile(path, disposition, name string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return ctx.NotFound()
+	}
+	if err != nil {
+		return ctx.InternalServerError(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return ctx.InternalServerError(err)
+	}
+	if info.IsDir() {
+		return ctx.NotFound()
+	}
+	ctx.Response.SetHeader("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+	ctx.Response.SetHeader("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	ctx.Response.SetHeader("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	if r, ok := ctx.Request.(*request.HTTP); ok {
+		if s, ok := ctx.Response.(contentServer); ok {
+			s.ServeContent(r.Raw(), name, info.ModTime(), f)
+			return nil
+		}
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return ctx.Stream(contentType, f)
+}
+
+// cliOutput writes CLI response to the file given by OutputFlag, the returned func closes it
+func cliOutput(ctx *Ctx) (func(), error) {
+	name := ctx.Flag(OutputFlag)
+	res, ok := ctx.Response.(*response.CLI)
+	if name == "" || !ok {
+		return func() {}, nil
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	res.SetOutput(f)
+	return func() { f.Close() }, nil
+}
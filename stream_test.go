@@ -0,0 +1,115 @@
+package wrap
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+	"github.com/torniker/wrap/response"
+)
+
+func TestStream(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Get("export", func(c *Ctx) error {
+		return c.Stream("text/csv", strings.NewReader("a,b\n1,2\n"))
+	})
+	p.Get("chunks", func(c *Ctx) error {
+		w := c.Response.Writer()
+		for _, chunk := range []string{"one\n", "two\n"} {
+			io.WriteString(w, chunk)
+			err := c.Response.Flush()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export", nil))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("text/csv", w.Header().Get("Content-Type"))
+	assert.Equal("a,b\n1,2\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chunks", nil))
+	assert.True(w.Flushed)
+	assert.Equal("one\ntwo\n", w.Body.String())
+
+	u, _ := url.Parse("/export")
+	ctx := p.NewCtx(request.NewRequest(request.GET, u, nil), response.NewResponse())
+	assert.NoError(p.handle(ctx))
+	assert.Equal([]byte("a,b\n1,2\n"), ctx.Response.Output())
+	assert.Equal(8, ctx.Response.Size())
+}
+
+func TestFile(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "report.txt")
+	assert.NoError(os.WriteFile(path, []byte("0123456789"), 0600))
+	p := New()
+	p.Get("report", func(c *Ctx) error {
+		return c.Attachment(path, "report 2020.txt")
+	})
+	p.Get("missing", func(c *Ctx) error {
+		return c.File(filepath.Join(filepath.Dir(path), "missing.txt"))
+	})
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/report", nil))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("0123456789", w.Body.String())
+	assert.Equal(`attachment; filename="report 2020.txt"`, w.Header().Get("Content-Disposition"))
+	assert.Equal("text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(etag)
+
+	r := httptest.NewRequest(http.MethodGet, "/report", nil)
+	r.Header.Set("Range", "bytes=2-4")
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.Equal(http.StatusPartialContent, w.Code)
+	assert.Equal("234", w.Body.String())
+
+	r = httptest.NewRequest(http.MethodGet, "/report", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	assert.Equal(http.StatusNotModified, w.Code)
+
+	w = httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(http.StatusNotFound, w.Code)
+}
+
+func TestCLIOutputFlag(t *testing.T) {
+	assert := assert.New(t)
+	out := filepath.Join(t.TempDir(), "out.csv")
+	p := New()
+	p.Get("export", func(c *Ctx) error {
+		return c.Stream("text/csv", strings.NewReader("a,b\n"))
+	})
+	u, _ := url.Parse("http://app.cli/export?output=" + url.QueryEscape(out))
+	ctx := p.NewCtx(request.NewCLI(request.GET, u, nil), response.NewCLI())
+	closeOutput, err := cliOutput(ctx)
+	assert.NoError(err)
+	p.serve(ctx)
+	closeOutput()
+	b, err := os.ReadFile(out)
+	assert.NoError(err)
+	assert.Equal("a,b\n", string(b))
+
+	var buf bytes.Buffer
+	res := response.NewCLI()
+	res.SetOutput(&buf)
+	assert.NoError(res.Stream(strings.NewReader("x")))
+	assert.Equal("x", buf.String())
+	assert.Equal(1, res.Size())
+}
@@ -0,0 +1,99 @@
+package wrap
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// ListenUnix listens on unix socket at path with file permissions mode, e.g. 0600 for admin endpoints.
+// Socket file left by a crashed process is removed, socket with a live listener is reported as in use.
+// The socket is created in a private directory next to path and moved to path once it has the mode,
+// so it is never reachable with the default permissions. Names of the directory and the socket in it
+// are short so the temporary path is not longer than path unless its name has less than 5 characters.
+// The file is removed when the listener is closed.
+func ListenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	fi, err := os.Lstat(path)
+	if err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%v exists and is not a socket", path)
+		}
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%v is in use", path)
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dir, err := privateDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "s")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	l.SetUnlinkOnClose(false)
+	err = os.Chmod(tmp, mode)
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &unixListener{UnixListener: l, path: path}, nil
+}
+
+// privateDir creates directory only the user can access in parent, its name is the shortest free one of .s0, .s1, ...
+// since socket paths are limited to about 100 bytes
+func privateDir(parent string) (string, error) {
+	for i := 0; ; i++ {
+		dir := filepath.Join(parent, ".s"+strconv.Itoa(i))
+		err := os.Mkdir(dir, 0700)
+		if err == nil {
+			return dir, nil
+		}
+		if !os.IsExist(err) || i == 1000 {
+			return "", err
+		}
+	}
+}
+
+// unixListener removes the socket file moved to path when it is closed
+type unixListener struct {
+	*net.UnixListener
+	path string
+	once sync.Once
+}
+
+// Addr returns address of the socket at path
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+// Close closes the listener and removes the socket file
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.once.Do(func() {
+		os.Remove(l.path)
+	})
+	return err
+}
+
+// StartUnix serves the app on unix socket at path, it returns nil after the app is shut down
+func (p *Prog) StartUnix(path string, mode os.FileMode) error {
+	l, err := ListenUnix(path, mode)
+	if err != nil {
+		return err
+	}
+	return p.Serve(l)
+}
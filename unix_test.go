@@ -0,0 +1,95 @@
+package wrap
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeUnix(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "admin.sock")
+
+	// stale socket of a crashed process
+	stale, err := net.Listen("unix", path)
+	assert.NoError(err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := ListenUnix(path, 0600)
+	assert.NoError(err)
+	fi, err := os.Stat(path)
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0600), fi.Mode().Perm())
+	entries, err := os.ReadDir(dir)
+	assert.NoError(err)
+	assert.Len(entries, 1)
+	assert.Equal(path, l.Addr().String())
+	_, err = ListenUnix(path, 0600)
+	assert.EqualError(err, path+" is in use")
+
+	p := New()
+	p.Server.ReadTimeout = time.Minute
+	p.Get("health", func(c *Ctx) error {
+		return c.JSON("ok")
+	})
+	done := make(chan error)
+	go func() {
+		done <- p.Serve(l)
+	}()
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", path)
+		},
+	}}
+	res, err := client.Get("http://admin/health")
+	assert.NoError(err)
+	b, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(http.StatusOK, res.StatusCode)
+	assert.JSONEq(`{"data":"ok"}`, string(b))
+	assert.Equal(time.Minute, p.Server.ReadTimeout)
+	assert.Equal(DefaultWriteTimeout, p.Server.WriteTimeout)
+
+	assert.NoError(p.Shutdown(context.Background()))
+	assert.NoError(<-done)
+	_, err = os.Stat(path)
+	assert.True(os.IsNotExist(err))
+}
+
+func TestListenUnixLongPath(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	// socket paths are limited to 107 bytes on linux
+	name := "admin.sock"
+	if len(dir) > 90 {
+		t.Skip("temporary directory is too long")
+	}
+	dir = filepath.Join(dir, strings.Repeat("d", 106-len(dir)-2-len(name)))
+	assert.NoError(os.Mkdir(dir, 0700))
+	// a directory created by someone else is skipped
+	assert.NoError(os.Mkdir(filepath.Join(dir, ".s0"), 0777))
+	path := filepath.Join(dir, name)
+	assert.Len(path, 106)
+	l, err := ListenUnix(path, 0600)
+	if assert.NoError(err) {
+		conn, err := net.Dial("unix", path)
+		assert.NoError(err)
+		conn.Close()
+		l.Close()
+	}
+	entries, err := os.ReadDir(dir)
+	assert.NoError(err)
+	if assert.Len(entries, 1) {
+		assert.Equal(".s0", entries[0].Name())
+	}
+}
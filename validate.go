@@ -0,0 +1,401 @@
+package wrap
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidatorFunc checks value against the rule param and returns error describing the violation
+type ValidatorFunc func(v reflect.Value, param string) error
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var builtinValidators = map[string]ValidatorFunc{
+	"min":   validateMin,
+	"max":   validateMax,
+	"regex": validateRegex,
+	"enum":  validateEnum,
+}
+
+// builtinParams check params of builtin rules against the field type once it is compiled
+var builtinParams = map[string]func(t reflect.Type, param string) error{
+	"min":   checkNumberParam,
+	"max":   checkNumberParam,
+	"regex": checkRegexParam,
+}
+
+// Validator registers custom validation rule which can be used in validate tags
+func (p *Prog) Validator(name string, f ValidatorFunc) {
+	p.validators[name] = f
+	p.rules.Range(func(t, _ interface{}) bool {
+		p.rules.Delete(t)
+		return true
+	})
+}
+
+// Validate checks v against validate tags and responds with ErrorUnprocessableEntity on violations,
+// invalid tags are responded as internal server error
+func (ctx *Ctx) Validate(v interface{}) error {
+	errs, err := ctx.Prog.Validate(v)
+	if err != nil {
+		return ctx.InternalServerError(err)
+	}
+	if len(errs) > 0 {
+		return ctx.UnprocessableEntity(errs)
+	}
+	return nil
+}
+
+// Validate returns violations of validate tags of v and its nested structs, slices and maps.
+// Rules are separated by comma: required, min=N, max=N, enum=a|b|c, regex=EXPR and custom ones.
+// regex has to be the last rule since the expression may contain commas.
+// Rules other than required are skipped for nil pointers and empty strings, slices and maps.
+// Tags are compiled once per type, unknown rules and invalid params are returned as error.
+func (p *Prog) Validate(v interface{}) (FieldErrors, error) {
+	var errs FieldErrors
+	err := p.validate(reflect.ValueOf(v), nil, &errs)
+	if err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+func (p *Prog) validate(v reflect.Value, path []string, errs *FieldErrors) error {
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return nil
+		}
+		fields, err := p.structRules(v.Type())
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			fv := v.Field(f.index)
+			if f.anonymous {
+				err = p.validate(fv, path, errs)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			fieldPath := append(path[:len(path):len(path)], f.name)
+			if !check(fv, f.rules, fieldPath, errs) {
+				continue
+			}
+			err = p.validate(fv, fieldPath, errs)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			err := p.validate(v.Index(i), append(path[:len(path):len(path)], strconv.Itoa(i)), errs)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			err := p.validate(iter.Value(), append(path[:len(path):len(path)], iter.Key().String()), errs)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// check applies rules to v, it returns false on the first violation
+func check(v reflect.Value, rules []rule, path []string, errs *FieldErrors) bool {
+	for _, r := range rules {
+		if r.f == nil {
+			if isEmpty(v) {
+				*errs = append(*errs, FieldError{Path: path, Message: "is required"})
+				return false
+			}
+			continue
+		}
+		if isAbsent(v) {
+			continue
+		}
+		err := r.f(v, r.param)
+		if err != nil {
+			*errs = append(*errs, FieldError{Path: path, Message: err.Error()})
+			return false
+		}
+	}
+	return true
+}
+
+// fieldRules are compiled rules of a struct field, fields of embedded structs are validated by their own rules
+type fieldRules struct {
+	index     int
+	name      string
+	anonymous bool
+	rules     []rule
+}
+
+// structRules returns compiled rules of the fields of t and structs it contains,
+// they are cached until a validator is registered
+func (p *Prog) structRules(t reflect.Type) ([]fieldRules, error) {
+	if fields, ok := p.rules.Load(t); ok {
+		return fields.([]fieldRules), nil
+	}
+	var fields []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			fields = append(fields, fieldRules{index: i, anonymous: true})
+			continue
+		}
+		tag := f.Tag.Get("validate")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		rules, err := p.compileRules(f.Type, tag)
+		if err != nil {
+			return nil, fmt.Errorf("wrap: validate tag of %v.%v: %v", t, f.Name, err)
+		}
+		fields = append(fields, fieldRules{index: i, name: fieldName(f), rules: rules})
+	}
+	// t is stored before its fields are compiled so that recursive types are compiled once
+	p.rules.Store(t, fields)
+	for _, f := range fields {
+		ft := t.Field(f.index).Type
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array || ft.Kind() == reflect.Map {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct || ft == timeType {
+			continue
+		}
+		_, err := p.structRules(ft)
+		if err != nil {
+			p.rules.Delete(t)
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// compileRules resolves validators of the tag rules and checks params of builtin ones
+func (p *Prog) compileRules(t reflect.Type, tag string) ([]rule, error) {
+	rules := parseRules(tag)
+	for i, r := range rules {
+		if r.name == "required" {
+			continue
+		}
+		f, ok := p.validators[r.name]
+		if !ok {
+			f, ok = builtinValidators[r.name]
+			if !ok {
+				return nil, fmt.Errorf("unknown validation rule %q", r.name)
+			}
+			if checkParam, ok := builtinParams[r.name]; ok {
+				err := checkParam(t, r.param)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		rules[i].f = f
+	}
+	return rules, nil
+}
+
+// rule is a validation rule of tag, f is nil for required
+type rule struct {
+	name  string
+	param string
+	f     ValidatorFunc
+}
+
+func parseRules(tag string) []rule {
+	var rules []rule
+	for tag != "" {
+		var r string
+		if strings.HasPrefix(tag, "regex=") {
+			r, tag = tag, ""
+		} else if i := strings.Index(tag, ","); i >= 0 {
+			r, tag = tag[:i], tag[i+1:]
+		} else {
+			r, tag = tag, ""
+		}
+		parts := strings.SplitN(strings.TrimSpace(r), "=", 2)
+		if parts[0] == "" {
+			continue
+		}
+		rl := rule{name: parts[0]}
+		if len(parts) == 2 {
+			rl.param = parts[1]
+		}
+		rules = append(rules, rl)
+	}
+	return rules
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// isAbsent reports whether v holds no value to validate
+func isAbsent(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	}
+	return false
+}
+
+func validateMin(v reflect.Value, param string) error {
+	n, _ := strconv.ParseFloat(param, 64)
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.String:
+		if float64(utf8.RuneCountInString(v.String())) < n {
+			return fmt.Errorf("must be at least %v characters long", param)
+		}
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if float64(v.Len()) < n {
+			return fmt.Errorf("must contain at least %v items", param)
+		}
+	default:
+		x, ok := number(v)
+		if !ok {
+			return errors.New("must be a number")
+		}
+		if x < n {
+			return fmt.Errorf("must be at least %v", param)
+		}
+	}
+	return nil
+}
+
+func validateMax(v reflect.Value, param string) error {
+	n, _ := strconv.ParseFloat(param, 64)
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.String:
+		if float64(utf8.RuneCountInString(v.String())) > n {
+			return fmt.Errorf("must be at most %v characters long", param)
+		}
+	case reflect.Slice, reflect.Map, reflect.Array:
+		if float64(v.Len()) > n {
+			return fmt.Errorf("must contain at most %v items", param)
+		}
+	default:
+		x, ok := number(v)
+		if !ok {
+			return errors.New("must be a number")
+		}
+		if x > n {
+			return fmt.Errorf("must be at most %v", param)
+		}
+	}
+	return nil
+}
+
+// checkNumberParam checks that param of min and max is a number and the field has length or is a number,
+// interfaces are checked when they are validated
+func checkNumberParam(t reflect.Type, param string) error {
+	_, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return fmt.Errorf("invalid param %q, expected number", param)
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array, reflect.Interface,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return nil
+	}
+	return fmt.Errorf("min and max rules do not support %v", t)
+}
+
+// number returns v as float64, it is false for values of interfaces which are not numbers
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+var regexps sync.Map
+
+// checkRegexParam compiles the expression so that it is matched without compiling again
+func checkRegexParam(_ reflect.Type, param string) error {
+	re, err := regexp.Compile(param)
+	if err != nil {
+		return err
+	}
+	regexps.LoadOrStore(param, re)
+	return nil
+}
+
+func validateRegex(v reflect.Value, param string) error {
+	re, ok := regexps.Load(param)
+	if !ok {
+		err := checkRegexParam(nil, param)
+		if err != nil {
+			return err
+		}
+		re, _ = regexps.Load(param)
+	}
+	if !re.(*regexp.Regexp).MatchString(fmt.Sprint(indirect(v).Interface())) {
+		return fmt.Errorf("must match %v", param)
+	}
+	return nil
+}
+
+func validateEnum(v reflect.Value, param string) error {
+	s := fmt.Sprint(indirect(v).Interface())
+	options := strings.Split(param, "|")
+	for _, o := range options {
+		if s == o {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %v", strings.Join(options, ", "))
+}
@@ -0,0 +1,308 @@
+package wrap
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/torniker/wrap/request"
+	"github.com/torniker/wrap/response"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = (wsPongWait * 9) / 10
+	wsMaxMessageSize = 1 << 20
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// ServeWS upgrades http connection to websocket and dispatches every received message as a request
+func (p *Prog) ServeWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		p.Logger.Error("websocket upgrade failed", "error", err)
+		return
+	}
+	c := &WSConn{
+		prog:    p,
+		conn:    conn,
+		done:    make(chan struct{}),
+		pinging: make(chan struct{}),
+	}
+	c.context, c.cancel = context.WithCancel(p.lifecycle.base)
+	// the deadline is set before the connection is added, so it does not override the one set on shutdown
+	conn.SetReadDeadline(time.Now().Add(p.Hub.pongWait))
+	p.Hub.add(c)
+	go c.ping()
+	c.listen()
+}
+
+// WSConn is websocket connection of a client
+type WSConn struct {
+	prog     *Prog
+	conn     *websocket.Conn
+	mu       sync.Mutex
+	inflight sync.WaitGroup
+	done     chan struct{}
+	pinging  chan struct{}
+	context  context.Context
+	cancel   context.CancelFunc
+}
+
+// Push sends server initiated event to the client
+func (c *WSConn) Push(event string, body interface{}) error {
+	_, err := c.send(response.WSReply{
+		Event: event,
+		Body:  body,
+	})
+	return err
+}
+
+// send writes frame as JSON text message and returns its size
+func (c *WSConn) send(frame interface{}) (int, error) {
+	b, err := json.Marshal(frame)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return len(b), c.conn.WriteMessage(websocket.TextMessage, b)
+}
+
+// listen reads messages until the connection is closed, each message is handled in its own goroutine
+func (c *WSConn) listen() {
+	defer c.close()
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetPongHandler(func(string) error {
+		if c.prog.stopping() {
+			return nil
+		}
+		return c.conn.SetReadDeadline(time.Now().Add(c.prog.Hub.pongWait))
+	})
+	for !c.prog.stopping() {
+		var m request.WSMessage
+		err := c.conn.ReadJSON(&m)
+		if err != nil {
+			switch err.(type) {
+			case *json.SyntaxError, *json.UnmarshalTypeError:
+				c.send(response.WSReply{
+					Status: http.StatusBadRequest,
+					Body:   ErrorBadRequest{Message: "invalid message"},
+				})
+				continue
+			}
+			return
+		}
+		if c.prog.stopping() {
+			return
+		}
+		c.inflight.Add(1)
+		go func() {
+			defer c.inflight.Done()
+			c.dispatch(m)
+		}()
+	}
+}
+
+func (c *WSConn) dispatch(m request.WSMessage) {
+	res := response.NewWS(m.ID, c.send)
+	action, u, ok := parseFrame(m, res)
+	if !ok {
+		return
+	}
+	ctx := c.prog.NewCtx(request.NewWS(action, u, m), res)
+	ctx.conn = c
+	ctx.context = c.context
+	c.prog.serve(ctx)
+}
+
+// parseFrame returns action and url of request frame m, invalid frames are replied with res
+func parseFrame(m request.WSMessage, res *response.WS) (request.Action, *url.URL, bool) {
+	action := request.NewActionFromString(m.Action)
+	if !action.IsValid() {
+		res.SetStatus(http.StatusBadRequest)
+		res.Write(ErrorBadRequest{Message: "invalid action"})
+		return action, nil, false
+	}
+	u, err := url.Parse(m.Path)
+	if err != nil {
+		res.SetStatus(http.StatusBadRequest)
+		res.Write(ErrorBadRequest{Message: "invalid path"})
+		return action, nil, false
+	}
+	return action, u, true
+}
+
+func (c *WSConn) ping() {
+	defer close(c.pinging)
+	ticker := time.NewTicker(c.prog.Hub.pingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.mu.Lock()
+			err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+			c.mu.Unlock()
+			if err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// close waits for replies of in-flight messages and sends close frame on shutdown, otherwise the client
+// is gone and contexts of in-flight messages are canceled since nobody can receive their replies anymore
+func (c *WSConn) close() {
+	if c.prog.stopping() {
+		c.inflight.Wait()
+		c.cancel()
+		c.mu.Lock()
+		c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down"), time.Now().Add(wsWriteWait))
+		c.mu.Unlock()
+	} else {
+		c.cancel()
+		c.inflight.Wait()
+	}
+	close(c.done)
+	<-c.pinging
+	c.prog.Hub.remove(c)
+	c.conn.Close()
+}
+
+// Hub keeps track of websocket connections and their subscriptions
+type Hub struct {
+	mu     sync.RWMutex
+	conns  map[*WSConn]struct{}
+	topics map[string]map[*WSConn]struct{}
+	// connections which do not answer pings sent every pingPeriod within pongWait are closed
+	pingPeriod time.Duration
+	pongWait   time.Duration
+}
+
+// NewHub returns empty hub
+func NewHub() *Hub {
+	return &Hub{
+		conns:      make(map[*WSConn]struct{}),
+		topics:     make(map[string]map[*WSConn]struct{}),
+		pingPeriod: wsPingPeriod,
+		pongWait:   wsPongWait,
+	}
+}
+
+// Subscribe adds connection to the topic subscribers
+func (h *Hub) Subscribe(topic string, c *WSConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*WSConn]struct{})
+	}
+	h.topics[topic][c] = struct{}{}
+}
+
+// Unsubscribe removes connection from the topic subscribers
+func (h *Hub) Unsubscribe(topic string, c *WSConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.topics[topic], c)
+	if len(h.topics[topic]) == 0 {
+		delete(h.topics, topic)
+	}
+}
+
+// Publish pushes event with the topic name to every subscribed connection
+func (h *Hub) Publish(topic string, body interface{}) {
+	h.mu.RLock()
+	conns := make([]*WSConn, 0, len(h.topics[topic]))
+	for c := range h.topics[topic] {
+		conns = append(conns, c)
+	}
+	h.mu.RUnlock()
+	for _, c := range conns {
+		err := c.Push(topic, body)
+		if err != nil {
+			c.prog.Logger.Error("websocket push failed", "error", err)
+		}
+	}
+}
+
+// closeAll stops reading messages of every connection, connections send close frame after replies
+// of their in-flight messages are sent
+func (h *Hub) closeAll() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.conns {
+		// deadline of the net connection may be set while the read loop is blocked
+		c.conn.UnderlyingConn().SetReadDeadline(time.Now())
+	}
+}
+
+// wait waits until every connection is closed or ctx is done
+func (h *Hub) wait(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		h.mu.RLock()
+		n := len(h.conns)
+		h.mu.RUnlock()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (h *Hub) add(c *WSConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conns[c] = struct{}{}
+}
+
+func (h *Hub) remove(c *WSConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns, c)
+	for topic, conns := range h.topics {
+		delete(conns, c)
+		if len(conns) == 0 {
+			delete(h.topics, topic)
+		}
+	}
+}
+
+// Subscribe subscribes websocket connection of the request to the topic
+func (ctx *Ctx) Subscribe(topic string) error {
+	if ctx.conn == nil {
+		return ctx.BadRequest("subscriptions require websocket connection")
+	}
+	ctx.Prog.Hub.Subscribe(topic, ctx.conn)
+	return nil
+}
+
+// Unsubscribe unsubscribes websocket connection of the request from the topic
+func (ctx *Ctx) Unsubscribe(topic string) {
+	if ctx.conn == nil {
+		return
+	}
+	ctx.Prog.Hub.Unsubscribe(topic, ctx.conn)
+}
+
+// Publish pushes event to websocket connections subscribed to the topic
+func (p *Prog) Publish(topic string, body interface{}) {
+	p.Hub.Publish(topic, body)
+}
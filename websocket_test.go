@@ -0,0 +1,202 @@
+package wrap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/torniker/wrap/request"
+)
+
+func TestWebSocket(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Post("echo", func(c *Ctx) error {
+		var v map[string]string
+		err := c.Request.Bind(&v)
+		if err != nil {
+			return c.BadRequest(err.Error())
+		}
+		return c.JSON(v)
+	})
+	p.Get("negotiated", func(c *Ctx) error {
+		return c.Respond(map[string]string{"a": "b"})
+	})
+	p.Post("subscribe/:topic", func(c *Ctx) error {
+		return c.Subscribe(c.Param("topic"))
+	})
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	// connections are closed before the next test replaces the app
+	defer p.Shutdown(context.Background())
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	assert.NoError(err)
+	defer conn.Close()
+
+	assert.NoError(conn.WriteJSON(request.WSMessage{ID: "1", Action: "post", Path: "echo", Body: []byte(`{"a":"b"}`)}))
+	var reply map[string]interface{}
+	assert.NoError(conn.ReadJSON(&reply))
+	assert.Equal("1", reply["id"])
+	assert.Equal(float64(http.StatusOK), reply["status"])
+	assert.Equal(map[string]interface{}{"data": map[string]interface{}{"a": "b"}}, reply["body"])
+
+	assert.NoError(conn.WriteJSON(request.WSMessage{ID: "4", Action: "get", Path: "negotiated", Flags: map[string][]string{FormatFlag: {"yaml"}}}))
+	reply = nil
+	assert.NoError(conn.ReadJSON(&reply))
+	assert.Equal("4", reply["id"])
+	assert.Equal("application/yaml", reply["headers"].(map[string]interface{})["Content-Type"])
+	assert.Equal("data:\n    a: b\n", reply["body"])
+
+	assert.NoError(conn.WriteJSON(request.WSMessage{ID: "2", Action: "get", Path: "missing"}))
+	reply = nil
+	assert.NoError(conn.ReadJSON(&reply))
+	assert.Equal("2", reply["id"])
+	assert.Equal(float64(http.StatusNotFound), reply["status"])
+
+	assert.NoError(conn.WriteJSON(request.WSMessage{ID: "3", Action: "post", Path: "subscribe/orders"}))
+	reply = nil
+	assert.NoError(conn.ReadJSON(&reply))
+	assert.Equal("3", reply["id"])
+	p.Publish("orders", "created")
+	reply = nil
+	assert.NoError(conn.ReadJSON(&reply))
+	assert.Equal("orders", reply["event"])
+	assert.Equal("created", reply["body"])
+}
+
+func dialWS(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestWebSocketConcurrent(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	const n = 5
+	var started sync.WaitGroup
+	started.Add(n)
+	p.Get("wait/:id", func(c *Ctx) error {
+		// every message is handled before any of them replies
+		started.Done()
+		started.Wait()
+		return c.JSON(c.Param("id"))
+	})
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	// connections are closed before the next test replaces the app
+	defer p.Shutdown(context.Background())
+	conn := dialWS(t, srv)
+	defer conn.Close()
+	for i := 0; i < n; i++ {
+		id := strconv.Itoa(i)
+		assert.NoError(conn.WriteJSON(request.WSMessage{ID: id, Action: "get", Path: "wait/" + id}))
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	got := map[string]interface{}{}
+	for i := 0; i < n; i++ {
+		var reply map[string]interface{}
+		assert.NoError(conn.ReadJSON(&reply))
+		got[reply["id"].(string)] = reply["body"].(map[string]interface{})["data"]
+	}
+	assert.Equal(map[string]interface{}{"0": "0", "1": "1", "2": "2", "3": "3", "4": "4"}, got)
+}
+
+func TestWebSocketPing(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	p.Hub.pongWait, p.Hub.pingPeriod = 300*time.Millisecond, 100*time.Millisecond
+	p.Get("ping", func(c *Ctx) error {
+		return c.JSON("pong")
+	})
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	// connections are closed before the next test replaces the app
+	defer p.Shutdown(context.Background())
+
+	// client answering pings stays connected longer than the pong wait
+	conn := dialWS(t, srv)
+	defer conn.Close()
+	pings := make(chan struct{}, 10)
+	conn.SetPingHandler(func(data string) error {
+		pings <- struct{}{}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	replies := make(chan map[string]interface{})
+	go func() {
+		for {
+			var reply map[string]interface{}
+			if conn.ReadJSON(&reply) != nil {
+				close(replies)
+				return
+			}
+			replies <- reply
+		}
+	}()
+	time.Sleep(2 * p.Hub.pongWait)
+	assert.NotEmpty(pings)
+	assert.NoError(conn.WriteJSON(request.WSMessage{ID: "1", Action: "get", Path: "ping"}))
+	reply := <-replies
+	assert.Equal("1", reply["id"])
+
+	// client ignoring pings is disconnected after the pong wait
+	silent := dialWS(t, srv)
+	defer silent.Close()
+	silent.SetPingHandler(func(string) error { return nil })
+	silent.SetReadDeadline(time.Now().Add(5 * time.Second))
+	start := time.Now()
+	_, _, err := silent.ReadMessage()
+	assert.Error(err)
+	assert.Less(time.Since(start), 2*time.Second)
+
+	assert.NoError(p.Shutdown(context.Background()))
+	_, ok := <-replies
+	assert.False(ok)
+}
+
+func TestWebSocketShutdown(t *testing.T) {
+	assert := assert.New(t)
+	p := New()
+	started := make(chan struct{})
+	p.Get("slow", func(c *Ctx) error {
+		close(started)
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-c.Context().Done():
+			return c.Context().Err()
+		}
+		return c.JSON("done")
+	})
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	// connections are closed before the next test replaces the app
+	defer p.Shutdown(context.Background())
+	conn := dialWS(t, srv)
+	defer conn.Close()
+	assert.NoError(conn.WriteJSON(request.WSMessage{ID: "1", Action: "get", Path: "slow"}))
+	<-started
+	shutdown := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- p.Shutdown(ctx)
+	}()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var reply map[string]interface{}
+	assert.NoError(conn.ReadJSON(&reply))
+	assert.Equal("1", reply["id"])
+	assert.Equal(float64(http.StatusOK), reply["status"])
+	assert.Equal(map[string]interface{}{"data": "done"}, reply["body"])
+	_, _, err := conn.ReadMessage()
+	assert.True(websocket.IsCloseError(err, websocket.CloseGoingAway), "%v", err)
+	assert.NoError(<-shutdown)
+}